@@ -34,5 +34,45 @@ if not %errorlevel%==0 (
     exit /b 1
 )
 
+echo Building cmd/liqalert...
+go build -ldflags="-s -w" -o dist/liqalert_linux_amd64 ./cmd/liqalert
+if not %errorlevel%==0 (
+    echo compilation failed: cmd/liqalert
+    pause
+    exit /b 1
+)
+
+echo Building cmd/hotcoin...
+go build -ldflags="-s -w" -o dist/hotcoin_linux_amd64 ./cmd/hotcoin
+if not %errorlevel%==0 (
+    echo compilation failed: cmd/hotcoin
+    pause
+    exit /b 1
+)
+
+echo Building cmd/whaleanchor...
+go build -ldflags="-s -w" -o dist/whaleanchor_linux_amd64 ./cmd/whaleanchor
+if not %errorlevel%==0 (
+    echo compilation failed: cmd/whaleanchor
+    pause
+    exit /b 1
+)
+
+echo Building cmd/labelrules...
+go build -ldflags="-s -w" -o dist/labelrules_linux_amd64 ./cmd/labelrules
+if not %errorlevel%==0 (
+    echo compilation failed: cmd/labelrules
+    pause
+    exit /b 1
+)
+
+echo Building cmd/events...
+go build -ldflags="-s -w" -o dist/events_linux_amd64 ./cmd/events
+if not %errorlevel%==0 (
+    echo compilation failed: cmd/events
+    pause
+    exit /b 1
+)
+
 echo compilation succeeded, generated binaries in dist/.
 pause
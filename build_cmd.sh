@@ -17,4 +17,19 @@ go build -ldflags="-s -w" -o dist/fills_linux_amd64 ./cmd/fills
 echo "Building cmd/snapshot..."
 go build -ldflags="-s -w" -o dist/snapshot_linux_amd64 ./cmd/snapshot
 
+echo "Building cmd/liqalert..."
+go build -ldflags="-s -w" -o dist/liqalert_linux_amd64 ./cmd/liqalert
+
+echo "Building cmd/hotcoin..."
+go build -ldflags="-s -w" -o dist/hotcoin_linux_amd64 ./cmd/hotcoin
+
+echo "Building cmd/whaleanchor..."
+go build -ldflags="-s -w" -o dist/whaleanchor_linux_amd64 ./cmd/whaleanchor
+
+echo "Building cmd/labelrules..."
+go build -ldflags="-s -w" -o dist/labelrules_linux_amd64 ./cmd/labelrules
+
+echo "Building cmd/events..."
+go build -ldflags="-s -w" -o dist/events_linux_amd64 ./cmd/events
+
 echo "compilation succeeded, generated binaries in dist/."
@@ -4,9 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/hypercopy/crawler/internal/config"
 	"github.com/hypercopy/crawler/internal/database"
+	"github.com/hypercopy/crawler/internal/events"
 	"github.com/hypercopy/crawler/internal/follower"
 	"github.com/hypercopy/crawler/internal/logger"
 	"go.uber.org/zap"
@@ -14,6 +16,7 @@ import (
 
 func main() {
 	serverIP := flag.String("server-ip", "", "本机 IP，用于从 Redis 分配表获取负责的地址列表")
+	maxStaleness := flag.Duration("max-staleness", 30*time.Second, "成交距今超过该时长不再跟单（断线补拉的成交仍会通知），0 表示不限制")
 	flag.Parse()
 
 	if *serverIP == "" {
@@ -41,8 +44,11 @@ func main() {
 	}
 	defer rdb.Close()
 
-	zap.S().Infof("[main] server-ip=%s", *serverIP)
+	bus := events.NewBus(rdb, cfg.Events)
+	zap.S().Infof("[main] event bus mode=%s", bus.Mode())
 
-	f := follower.New(db, rdb, *serverIP)
+	zap.S().Infof("[main] server-ip=%s, max-staleness=%v", *serverIP, *maxStaleness)
+
+	f := follower.New(db, rdb, bus, *serverIP, *maxStaleness)
 	f.Run()
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/config"
+	"github.com/hypercopy/crawler/internal/database"
+	"github.com/hypercopy/crawler/internal/events"
+	"github.com/hypercopy/crawler/internal/hotcoin"
+	"github.com/hypercopy/crawler/internal/logger"
+	"go.uber.org/zap"
+)
+
+func main() {
+	interval := flag.Duration("interval", 5*time.Minute, "计算间隔")
+	top := flag.Int("top", 50, "推送到 Redis 的热门币种数量")
+	flag.Parse()
+
+	_, cleanup, err := logger.Init("hotcoin")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	cfg := config.Load()
+
+	db, err := database.NewPostgres(cfg.Postgres)
+	if err != nil {
+		zap.S().Fatalf("postgres: %v", err)
+	}
+
+	rdb, err := database.NewRedis(cfg.Redis)
+	if err != nil {
+		zap.S().Fatalf("redis: %v", err)
+	}
+	defer rdb.Close()
+
+	bus := events.NewBus(rdb, cfg.Events)
+	zap.S().Infof("[main] event bus mode=%s", bus.Mode())
+
+	zap.S().Infof("[main] interval=%s top=%d", *interval, *top)
+
+	j := hotcoin.New(db, rdb, bus, *interval, *top)
+	j.Run()
+}
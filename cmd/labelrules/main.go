@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hypercopy/crawler/internal/config"
+	"github.com/hypercopy/crawler/internal/database"
+	"github.com/hypercopy/crawler/internal/logger"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/snapshot"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func main() {
+	version := flag.Int("version", 0, "要试算/发布的规则版本号")
+	activate := flag.Bool("activate", false, "试算后将该版本设为 active（原 active 版本归档）")
+	seed := flag.Bool("seed", false, "将内置默认规则写入为新的 draft 版本")
+	rulesFile := flag.String("import", "", "从 JSON 文件导入规则为新的 draft 版本")
+	remark := flag.String("remark", "", "新版本的变更说明（配合 -seed / -import）")
+	flag.Parse()
+
+	_, cleanup, err := logger.Init("labelrules")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	cfg := config.Load()
+
+	db, err := database.NewPostgres(cfg.Postgres)
+	if err != nil {
+		zap.S().Fatalf("postgres: %v", err)
+	}
+
+	switch {
+	case *seed:
+		v, err := createDraft(db, snapshot.DefaultLabelRules(), *remark)
+		if err != nil {
+			zap.S().Fatalf("seed: %v", err)
+		}
+		fmt.Printf("Default rules saved as draft version %d.\n", v)
+		return
+	case *rulesFile != "":
+		data, err := os.ReadFile(*rulesFile)
+		if err != nil {
+			zap.S().Fatalf("read %s: %v", *rulesFile, err)
+		}
+		var rules model.LabelRules
+		if err := json.Unmarshal(data, &rules); err != nil {
+			zap.S().Fatalf("parse %s: %v", *rulesFile, err)
+		}
+		if err := snapshot.ValidateLabelRules(rules); err != nil {
+			zap.S().Fatalf("invalid rules: %v", err)
+		}
+		v, err := createDraft(db, rules, *remark)
+		if err != nil {
+			zap.S().Fatalf("import: %v", err)
+		}
+		fmt.Printf("Rules imported as draft version %d.\n", v)
+		return
+	}
+
+	if *version <= 0 {
+		fmt.Fprintln(os.Stderr, "-version is required")
+		os.Exit(1)
+	}
+
+	report, err := snapshot.DryRunLabelRules(db, *version)
+	if err != nil {
+		zap.S().Fatalf("dry run: %v", err)
+	}
+	printReport(report)
+
+	if !*activate {
+		return
+	}
+	if err := activateVersion(db, *version); err != nil {
+		zap.S().Fatalf("activate: %v", err)
+	}
+	fmt.Printf("Version %d is now active; the snapshot syncer picks it up next round.\n", *version)
+}
+
+func createDraft(db *gorm.DB, rules model.LabelRules, remark string) (int, error) {
+	var maxVersion int
+	if err := db.Model(&model.LabelRuleSet{}).Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+		return 0, err
+	}
+	set := model.LabelRuleSet{
+		Version: maxVersion + 1,
+		Status:  model.LabelRuleSetStatusDraft,
+		Rules:   rules,
+		Remark:  remark,
+	}
+	if err := db.Create(&set).Error; err != nil {
+		return 0, err
+	}
+	return set.Version, nil
+}
+
+func activateVersion(db *gorm.DB, version int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.LabelRuleSet{}).
+			Where("status = ?", model.LabelRuleSetStatusActive).
+			Update("status", model.LabelRuleSetStatusArchived).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&model.LabelRuleSet{}).Where("version = ?", version).
+			Update("status", model.LabelRuleSetStatusActive)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("version %d not found", version)
+		}
+		return nil
+	})
+}
+
+func printReport(r *snapshot.LabelDryRunReport) {
+	fmt.Printf("Dry run: version %d (active) -> version %d (candidate)\n", r.BaseVersion, r.CandidateVersion)
+	fmt.Printf("Traders evaluated: %d, relabeled: %d\n\n", r.Traders, r.Changed)
+
+	sort.Slice(r.Diffs, func(i, j int) bool {
+		if r.Diffs[i].Category != r.Diffs[j].Category {
+			return r.Diffs[i].Category < r.Diffs[j].Category
+		}
+		return r.Diffs[i].Label < r.Diffs[j].Label
+	})
+
+	fmt.Printf("%-16s %-18s %8s %8s %8s %8s\n", "CATEGORY", "LABEL", "BEFORE", "AFTER", "+ADDED", "-REMOVED")
+	for _, d := range r.Diffs {
+		fmt.Printf("%-16s %-18s %8d %8d %8d %8d\n", d.Category, d.Label, d.Before, d.After, d.Added, d.Removed)
+	}
+}
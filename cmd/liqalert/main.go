@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/config"
+	"github.com/hypercopy/crawler/internal/database"
+	"github.com/hypercopy/crawler/internal/events"
+	"github.com/hypercopy/crawler/internal/liqalert"
+	"github.com/hypercopy/crawler/internal/logger"
+	"go.uber.org/zap"
+)
+
+func main() {
+	thresholds := flag.String("thresholds", "10,5,2", "距强平百分比告警阈值，逗号分隔")
+	cooldown := flag.Duration("cooldown", 30*time.Minute, "同一持仓同一阈值的告警冷却时间")
+	refresh := flag.Duration("refresh", 30*time.Second, "跟单/跟踪交易员及其持仓的重新加载间隔")
+	flag.Parse()
+
+	levels, err := parseThresholds(*thresholds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-thresholds: %v\n", err)
+		os.Exit(1)
+	}
+
+	_, cleanup, err := logger.Init("liqalert")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	cfg := config.Load()
+
+	db, err := database.NewPostgres(cfg.Postgres)
+	if err != nil {
+		zap.S().Fatalf("postgres: %v", err)
+	}
+
+	rdb, err := database.NewRedis(cfg.Redis)
+	if err != nil {
+		zap.S().Fatalf("redis: %v", err)
+	}
+	defer rdb.Close()
+
+	bus := events.NewBus(rdb, cfg.Events)
+	zap.S().Infof("[main] event bus mode=%s", bus.Mode())
+
+	zap.S().Infof("[main] thresholds=%v%% cooldown=%s refresh=%s", levels, *cooldown, *refresh)
+
+	m := liqalert.New(db, rdb, bus, levels, *cooldown, *refresh)
+	m.Run()
+}
+
+func parseThresholds(s string) ([]float64, error) {
+	var out []float64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid threshold %q", part)
+		}
+		out = append(out, v)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("at least one threshold is required")
+	}
+	return out, nil
+}
@@ -4,16 +4,23 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/hypercopy/crawler/internal/config"
 	"github.com/hypercopy/crawler/internal/database"
+	"github.com/hypercopy/crawler/internal/events"
 	"github.com/hypercopy/crawler/internal/logger"
+	"github.com/hypercopy/crawler/internal/proxy"
 	"github.com/hypercopy/crawler/internal/snapshot"
 	"go.uber.org/zap"
 )
 
 func main() {
-	rate := flag.Int("rate", 5, "并发 worker 数量")
+	rate := flag.Float64("rate", 5, "API 每秒请求数上限（所有拉取 worker 合计，<= 0 不限速）")
+	fetchWorkers := flag.Int("fetch-workers", 5, "API 拉取并发 worker 数量")
+	statsWorkers := flag.Int("stats-workers", 3, "统计计算（数据库）并发 worker 数量")
+	useProxy := flag.Bool("proxy", false, "是否启用代理池")
+	positioningRetention := flag.Duration("positioning-retention", 90*24*time.Hour, "聪明钱持仓指数（coin_positioning）保留时长，<= 0 不清理")
 	flag.Parse()
 
 	_, cleanup, err := logger.Init("snapshot")
@@ -30,8 +37,27 @@ func main() {
 		zap.S().Fatalf("postgres: %v", err)
 	}
 
-	zap.S().Infof("[main] %d workers", *rate)
+	rdb, err := database.NewRedis(cfg.Redis)
+	if err != nil {
+		zap.S().Fatalf("redis: %v", err)
+	}
+	defer rdb.Close()
+
+	bus := events.NewBus(rdb, cfg.Events)
+	zap.S().Infof("[main] event bus mode=%s", bus.Mode())
+
+	var proxyMgr *proxy.Manager
+	if *useProxy {
+		proxyMgr, err = proxy.NewManager(db)
+		if err != nil {
+			zap.S().Fatalf("proxy manager: %v", err)
+		}
+		zap.S().Infof("[main] proxy enabled, %d proxies loaded", proxyMgr.Count())
+	} else {
+		zap.S().Info("[main] proxy disabled (direct connection)")
+	}
+	zap.S().Infof("[main] %d fetch workers, %d stats workers, %.2f req/s", *fetchWorkers, *statsWorkers, *rate, *positioningRetention)
 
-	s := snapshot.NewSyncer(db, *rate)
+	s := snapshot.NewSyncer(db, rdb, bus, proxyMgr, *fetchWorkers, *statsWorkers, *rate, *positioningRetention)
 	s.Run()
 }
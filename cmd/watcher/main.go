@@ -4,10 +4,15 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/hypercopy/crawler/internal/config"
 	"github.com/hypercopy/crawler/internal/database"
+	"github.com/hypercopy/crawler/internal/events"
 	"github.com/hypercopy/crawler/internal/logger"
+	"github.com/hypercopy/crawler/internal/proxy"
 	"github.com/hypercopy/crawler/internal/watcher"
 	"go.uber.org/zap"
 )
@@ -16,6 +21,17 @@ func main() {
 	rate := flag.Int("rate", 10, "每秒请求 clearinghouseState 的次数")
 	offset := flag.Int("offset", 0, "跳过排行榜前 N 名交易员")
 	limit := flag.Int("limit", 0, "监控的交易员数量（0 表示不限制）")
+	sizeChangePct := flag.Float64("size-change-pct", 10, "仓位大小变化超过该百分比时发布加减仓事件")
+	stream := flag.Bool("stream", false, "使用 WebSocket webData2 推送模式（订阅不下的地址回退到轮询）")
+	subsPerConn := flag.Int("subs-per-conn", 10, "推送模式下单条连接订阅的地址数（Hyperliquid 单 IP 最多跟踪 10 个用户）")
+	maxConns := flag.Int("max-conns", 0, "推送模式下的最大连接数（0 表示每个代理一条连接）")
+	useProxy := flag.Bool("proxy", false, "推送模式下是否通过代理池建立连接")
+	joinCluster := flag.Bool("cluster", false, "加入 watcher 集群，与其他实例按一致性哈希自动划分地址")
+	instanceID := flag.String("instance-id", "", "集群实例ID（默认 主机名-进程号）")
+	statsWindow := flag.String("stats-window", "allTime", "新开仓事件补充统计指标的窗口（day/week/month/allTime）")
+	minValue := flag.Float64("min-value", 0, "持仓价值低于该值（USD）的新开仓不发布")
+	minAccountPct := flag.Float64("min-account-pct", 0, "持仓价值占账户价值百分比低于该值的新开仓不发布")
+	labels := flag.String("labels", "", "只发布带有其中任一标签的交易员的新开仓（逗号分隔，空表示不限制）")
 	flag.Parse()
 
 	_, cleanup, err := logger.Init("watcher")
@@ -38,8 +54,56 @@ func main() {
 	}
 	defer rdb.Close()
 
-	zap.S().Infof("[main] rate=%d/s, offset=%d, limit=%d", *rate, *offset, *limit)
+	bus := events.NewBus(rdb, cfg.Events)
+	zap.S().Infof("[main] event bus mode=%s", bus.Mode())
 
-	w := watcher.New(db, rdb, *rate, *offset, *limit)
-	w.Run()
+	zap.S().Infof("[main] rate=%d/s, offset=%d, limit=%d, size-change=%.1f%%", *rate, *offset, *limit, *sizeChangePct)
+
+	w := watcher.New(db, rdb, bus, *rate, *offset, *limit, *sizeChangePct)
+	opts := watcher.EventOptions{
+		StatsWindow:   *statsWindow,
+		MinValue:      *minValue,
+		MinAccountPct: *minAccountPct,
+	}
+	for _, l := range strings.Split(*labels, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			opts.Labels = append(opts.Labels, l)
+		}
+	}
+	w.SetEventOptions(opts)
+	zap.S().Infof("[main] event options: window=%s, min-value=%.0f, min-account-pct=%.1f, labels=%v",
+		opts.StatsWindow, opts.MinValue, opts.MinAccountPct, opts.Labels)
+	if *joinCluster {
+		id := *instanceID
+		if id == "" {
+			host, _ := os.Hostname()
+			id = fmt.Sprintf("%s-%d", host, os.Getpid())
+		}
+		if err := w.JoinCluster(id); err != nil {
+			zap.S().Fatalf("%v", err)
+		}
+		zap.S().Infof("[main] joined watcher cluster as %s", id)
+	}
+
+	run := w.Run
+	if *stream {
+		var proxyMgr *proxy.Manager
+		if *useProxy {
+			proxyMgr, err = proxy.NewManager(db)
+			if err != nil {
+				zap.S().Fatalf("proxy manager: %v", err)
+			}
+			zap.S().Infof("[main] proxy enabled, %d proxies loaded", proxyMgr.Count())
+		}
+		zap.S().Infof("[main] stream mode: subs-per-conn=%d, max-conns=%d", *subsPerConn, *maxConns)
+		run = func() { w.RunStream(proxyMgr, *subsPerConn, *maxConns) }
+	}
+	go run()
+
+	// 收到退出信号后离开集群并从 main 返回，让日志 cleanup 与 Redis 关闭等 defer 正常执行
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	s := <-sig
+	zap.S().Infof("[main] received %v, shutting down", s)
+	w.LeaveCluster()
 }
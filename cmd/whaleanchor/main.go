@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/config"
+	"github.com/hypercopy/crawler/internal/database"
+	"github.com/hypercopy/crawler/internal/hyperliquid"
+	"github.com/hypercopy/crawler/internal/logger"
+	"github.com/hypercopy/crawler/internal/whaleanchor"
+	"go.uber.org/zap"
+)
+
+func main() {
+	interval := flag.Duration("interval", time.Hour, "计算间隔")
+	delay := flag.Duration("delay", 200*time.Millisecond, "每次 API 请求间隔")
+	volumeCoef := flag.Float64("volume-coef", whaleanchor.DefaultFormula.VolumeCoef, "24h 成交额系数")
+	oiCoef := flag.Float64("oi-coef", whaleanchor.DefaultFormula.OICoef, "未平仓量（USD）系数")
+	depthCoef := flag.Float64("depth-coef", whaleanchor.DefaultFormula.DepthCoef, "±1% 盘口深度系数")
+	minThreshold := flag.Float64("min-threshold", 0, "巨鲸阈值下限（USD）")
+	flag.Parse()
+
+	_, cleanup, err := logger.Init("whaleanchor")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
+	cfg := config.Load()
+
+	db, err := database.NewPostgres(cfg.Postgres)
+	if err != nil {
+		zap.S().Fatalf("postgres: %v", err)
+	}
+
+	formula := whaleanchor.Formula{
+		VolumeCoef:   *volumeCoef,
+		OICoef:       *oiCoef,
+		DepthCoef:    *depthCoef,
+		MinThreshold: *minThreshold,
+	}
+	zap.S().Infof("[main] interval=%s formula=max(%.4f*vol24h, %.4f*oi, %.4f*depth1pct, %.0f)",
+		*interval, formula.VolumeCoef, formula.OICoef, formula.DepthCoef, formula.MinThreshold)
+
+	j := whaleanchor.New(db, hyperliquid.NewClient(), formula, *interval, *delay)
+	j.Run()
+}
@@ -3,11 +3,13 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 )
 
 type Config struct {
 	Postgres PostgresConfig
 	Redis    RedisConfig
+	Events   EventsConfig
 }
 
 type PostgresConfig struct {
@@ -30,6 +32,12 @@ type RedisConfig struct {
 	DB       int
 }
 
+// EventsConfig 事件总线配置
+type EventsConfig struct {
+	Mode   string // pubsub / stream / both，见 events.Mode*
+	MaxLen int64  // 每个 Stream 保留的大致条数
+}
+
 func Load() *Config {
 	return &Config{
 		Postgres: PostgresConfig{
@@ -45,7 +53,20 @@ func Load() *Config {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       0,
 		},
+		Events: EventsConfig{
+			Mode:   getEnv("EVENT_BUS_MODE", "both"),
+			MaxLen: getEnvInt("EVENT_STREAM_MAXLEN", 100000),
+		},
+	}
+}
+
+func getEnvInt(key string, fallback int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
 	}
+	return fallback
 }
 
 func getEnv(key, fallback string) string {
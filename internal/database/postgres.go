@@ -58,8 +58,16 @@ func NewPostgres(cfg config.PostgresConfig) (*gorm.DB, error) {
 		&model.Leaderboard{},
 		&model.SystemSetting{},
 		&model.TraderStatistic{},
+		&model.TraderCoinStatistic{},
+		&model.LabelRuleSet{},
+		&model.TraderLabelHistory{},
+		&model.TraderScore{},
+		&model.TraderSnapshotHistory{},
+		&model.TraderPositionEvent{},
 		&model.FetchFailure{},
 		&model.HotCoin{},
+		&model.HotCoinHistory{},
+		&model.CoinPositioning{},
 		&model.CopyTradeRecord{},
 		&model.CopyTrading{},
 		&model.Server{},
@@ -69,40 +77,48 @@ func NewPostgres(cfg config.PostgresConfig) (*gorm.DB, error) {
 
 	// 为各表添加数据库级别注释
 	tableComments := map[string]string{
-		"traders":               "交易员信息表",
-		"trader_performances":   "交易员绩效表（按时间窗口聚合）",
-		"trader_account_values": "交易员账户价值历史表",
-		"trader_pnl_histories":  "交易员盈亏历史表",
-		"trader_fills":          "交易员成交记录表",
-		"trader_fundings":       "交易员资金费记录表",
-		"trader_orders":         "交易员历史委托记录表",
-		"proxy_pools":           "代理池表",
-		"completed_trades":      "已完成交易表（由 fills 聚合而来）",
-		"trader_positions":      "交易员当前持仓表",
-		"user":                  "用户表",
-		"admin":                 "后台管理员表",
-		"copy_trade_config":     "跟单交易配置表",
-		"wallet":                "钱包表",
-		"my_track_wallet":       "跟踪钱包表",
-		"position":              "持仓表",
-		"membership":            "会员表",
-		"notification":          "通知表",
-		"notification_read":     "通知已读记录表",
-		"cron_task":             "定时任务表",
-		"app_version":           "APP版本管理表",
-		"whale_anchor":          "巨鲸锚点表",
-		"user_app_key":          "用户AppID/AppSecret管理表",
-		"coin_market":           "币种行情数据表",
-		"asset_positions":       "交易员当前资产持仓表",
-		"trader_coin_holding":   "交易员当前持仓币种表",
-		"leaderboard":           "排行榜表",
-		"system_setting":        "系统设置表",
-		"trader_statistics":     "交易员统计指标表（按时间窗口聚合）",
-		"fetch_failures":        "数据获取失败表（最细粒度窗口仍超限，含 fills/orders/funding 类型）",
-		"hot_coin":              "热门币种表（按持仓交易员数量排名）",
-		"copy_trade_record":     "跟单记录表（每笔跟单操作的执行明细）",
-		"copy_trading":          "跟单持仓表（copyTradeConfig配置+trader_position部分字段+执行/订单状态）",
-		"server_management":     "服务器管理表（IP、用户名、密码）",
+		"traders":                 "交易员信息表",
+		"trader_performances":     "交易员绩效表（按时间窗口聚合）",
+		"trader_account_values":   "交易员账户价值历史表",
+		"trader_pnl_histories":    "交易员盈亏历史表",
+		"trader_fills":            "交易员成交记录表",
+		"trader_fundings":         "交易员资金费记录表",
+		"trader_orders":           "交易员历史委托记录表",
+		"proxy_pools":             "代理池表",
+		"completed_trades":        "已完成交易表（由 fills 聚合而来）",
+		"trader_positions":        "交易员当前持仓表",
+		"user":                    "用户表",
+		"admin":                   "后台管理员表",
+		"copy_trade_config":       "跟单交易配置表",
+		"wallet":                  "钱包表",
+		"my_track_wallet":         "跟踪钱包表",
+		"position":                "持仓表",
+		"membership":              "会员表",
+		"notification":            "通知表",
+		"notification_read":       "通知已读记录表",
+		"cron_task":               "定时任务表",
+		"app_version":             "APP版本管理表",
+		"whale_anchor":            "巨鲸锚点表",
+		"user_app_key":            "用户AppID/AppSecret管理表",
+		"coin_market":             "币种行情数据表",
+		"asset_positions":         "交易员当前资产持仓表",
+		"trader_coin_holding":     "交易员当前持仓币种表",
+		"leaderboard":             "排行榜表",
+		"system_setting":          "系统设置表",
+		"trader_statistics":       "交易员统计指标表（按时间窗口聚合）",
+		"trader_coin_statistics":  "交易员分币种统计表（按币种、时间窗口聚合）",
+		"label_rule_set":          "交易员标签规则版本表（声明式标签规则，snapshot 热加载 active 版本）",
+		"trader_label_history":    "交易员标签变更历史表（新增/移除及当时的指标快照）",
+		"trader_scores":           "交易员综合跟单价值评分表（按时间窗口排名）",
+		"trader_snapshot_history": "交易员快照历史表（杠杆/保证金/多空敞口时间序列，按保留策略降采样）",
+		"trader_position_events":  "交易员持仓变更事件表（对比相邻持仓快照得到的开/加/减/平/反手/杠杆/保证金模式变更）",
+		"fetch_failures":          "数据获取失败表（最细粒度窗口仍超限，含 fills/orders/funding 类型）",
+		"hot_coin":                "热门币种表（按持仓交易员数量排名）",
+		"hot_coin_history":        "热门币种历史表（每次计算的排名与持仓聚合，用于趋势图）",
+		"coin_positioning":        "聪明钱持仓指数时间序列表（按币种聚合跟踪交易员的多空持仓）",
+		"copy_trade_record":       "跟单记录表（每笔跟单操作的执行明细）",
+		"copy_trading":            "跟单持仓表（copyTradeConfig配置+trader_position部分字段+执行/订单状态）",
+		"server_management":       "服务器管理表（IP、用户名、密码）",
 	}
 	for table, comment := range tableComments {
 		if err := db.Exec("COMMENT ON TABLE " + table + " IS '" + comment + "'").Error; err != nil {
@@ -0,0 +1,337 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/config"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 事件总线模式
+//
+// 迁移顺序：先以 both 部署全部生产者（Stream 与 Pub/Sub 双写，消费者仍读 Pub/Sub），
+// 消费者全部支持 Stream 后切换为 stream
+const (
+	ModePubSub = "pubsub" // 仅 Pub/Sub，与迁移前行为一致
+	ModeStream = "stream" // 仅 Redis Streams，消费者通过消费组读取
+	ModeBoth   = "both"   // 生产者双写，消费者读 Pub/Sub
+)
+
+const (
+	streamPrefix     = "events:"
+	deadLetterPrefix = "events:dead:" // + topic，超过 maxDeliveries 仍处理失败的消息
+	groupStartID     = "0"            // 新建消费组从 Stream 开头读取，消费组创建前（如 both 模式双写期间）写入的事件不会丢失
+	readCount        = 100
+	readBlock        = 5 * time.Second
+	claimInterval    = 30 * time.Second
+	claimMinIdle     = time.Minute // 超过该时长未 ack 的消息由存活的消费者接管重试
+	maxDeliveries    = 5           // 投递次数超过该值的消息 ack 后转入死信 Stream
+	replayBatch      = 500
+	retryDelay       = 3 * time.Second
+)
+
+// Event 从总线读取到的事件；Pub/Sub 模式下只有 Topic 与 Data
+type Event struct {
+	ID      string          // Stream 消息ID
+	Topic   string          // 频道名（Stream 为 events:{Topic}）
+	Type    string          // 事件类型，见 types.go
+	Version int             // 事件结构版本
+	Time    int64           // 发布时间（毫秒时间戳）
+	Data    json.RawMessage // 事件内容，与 Pub/Sub 消息体相同
+}
+
+// Handler 返回 error 时消息不 ack，稍后重试
+type Handler func(ctx context.Context, e Event) error
+
+// Bus Redis 事件总线
+type Bus struct {
+	rdb    *redis.Client
+	mode   string
+	maxLen int64
+}
+
+// NewBus 按配置创建事件总线，未知模式按 both 处理
+func NewBus(rdb *redis.Client, cfg config.EventsConfig) *Bus {
+	mode := cfg.Mode
+	switch mode {
+	case ModePubSub, ModeStream, ModeBoth:
+	default:
+		zap.S().Warnf("[events] unknown mode %q, using %s", mode, ModeBoth)
+		mode = ModeBoth
+	}
+	return &Bus{rdb: rdb, mode: mode, maxLen: cfg.MaxLen}
+}
+
+func (b *Bus) Mode() string {
+	return b.mode
+}
+
+// StreamKey 频道对应的 Stream 键
+func StreamKey(topic string) string {
+	return streamPrefix + topic
+}
+
+// DeadLetterKey 频道对应的死信 Stream 键
+func DeadLetterKey(topic string) string {
+	return deadLetterPrefix + topic
+}
+
+// Publish 发布事件：stream/both 模式 XADD 到 events:{topic}（按 MAXLEN ~ 裁剪），
+// pubsub/both 模式将 payload 原样 PUBLISH 到 topic，保持旧消费者兼容
+func (b *Bus) Publish(ctx context.Context, topic string, t Type, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", t.Name, err)
+	}
+
+	if b.mode != ModePubSub {
+		err := b.rdb.XAdd(ctx, &redis.XAddArgs{
+			Stream: StreamKey(topic),
+			MaxLen: b.maxLen,
+			Approx: true,
+			Values: map[string]interface{}{
+				"type": t.Name,
+				"v":    t.Version,
+				"time": time.Now().UnixMilli(),
+				"data": string(data),
+			},
+		}).Err()
+		if err != nil {
+			return fmt.Errorf("xadd %s: %w", topic, err)
+		}
+	}
+	if b.mode != ModeStream {
+		if err := b.rdb.Publish(ctx, topic, string(data)).Err(); err != nil {
+			return fmt.Errorf("publish %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// Consume 阻塞消费 topic 直到 ctx 结束。stream 模式下通过消费组读取：
+// 先重新处理本消费者上次退出前未 ack 的消息，再读取新消息，并定期接管其他消费者超时未 ack 的消息；
+// 其他模式下订阅 Pub/Sub，处理失败的消息不会重试
+func (b *Bus) Consume(ctx context.Context, topic, group, consumer string, h Handler) error {
+	if b.mode != ModeStream {
+		return b.ConsumePubSub(ctx, topic, h)
+	}
+
+	key := StreamKey(topic)
+	if err := b.rdb.XGroupCreateMkStream(ctx, key, group, groupStartID).Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("create group %s/%s: %w", key, group, err)
+	}
+	zap.S().Infof("[events] consuming %s as %s/%s", key, group, consumer)
+
+	start := "0" // 先读本消费者的 pending 列表，读完后切换为 ">"
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) >= claimInterval {
+			b.claimStale(ctx, key, group, consumer, h)
+			lastClaim = time.Now()
+		}
+
+		streams, err := b.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: consumer,
+			Streams:  []string{key, start},
+			Count:    readCount,
+			Block:    readBlock,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			zap.S().Errorf("[events] xreadgroup %s: %v", key, err)
+			time.Sleep(retryDelay)
+			continue
+		}
+
+		var msgs []redis.XMessage
+		if len(streams) > 0 {
+			msgs = streams[0].Messages
+		}
+		if start != ">" {
+			if len(msgs) == 0 {
+				start = ">"
+				continue
+			}
+			// pending 中处理失败的消息留给 claimStale 重试，这里继续向后读
+			start = msgs[len(msgs)-1].ID
+		}
+		for _, msg := range msgs {
+			b.handle(ctx, topic, group, msg, h)
+		}
+	}
+	return ctx.Err()
+}
+
+// claimStale 接管消费组内空闲超过 claimMinIdle 的未 ack 消息（包括本消费者自己处理失败的消息），
+// 投递次数超过 maxDeliveries 的消息不再重试，转入死信 Stream
+func (b *Bus) claimStale(ctx context.Context, key, group, consumer string, h Handler) {
+	topic := strings.TrimPrefix(key, streamPrefix)
+	cursor := "0-0"
+	for {
+		msgs, next, err := b.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   key,
+			Group:    group,
+			Consumer: consumer,
+			MinIdle:  claimMinIdle,
+			Start:    cursor,
+			Count:    readCount,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				zap.S().Errorf("[events] xautoclaim %s: %v", key, err)
+			}
+			return
+		}
+		deliveries := b.deliveryCounts(ctx, key, group, consumer, msgs)
+		for _, msg := range msgs {
+			if n := deliveries[msg.ID]; n > maxDeliveries {
+				b.deadLetter(ctx, topic, group, msg, n)
+				continue
+			}
+			b.handle(ctx, topic, group, msg, h)
+		}
+		if next == "0-0" || len(msgs) == 0 {
+			return
+		}
+		cursor = next
+	}
+}
+
+// deliveryCounts 通过 XPENDING 查询刚接管的消息的投递次数；查询失败时返回空表，消息照常重试
+func (b *Bus) deliveryCounts(ctx context.Context, key, group, consumer string, msgs []redis.XMessage) map[string]int64 {
+	if len(msgs) == 0 {
+		return nil
+	}
+	pending, err := b.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   key,
+		Group:    group,
+		Start:    msgs[0].ID,
+		End:      msgs[len(msgs)-1].ID,
+		Count:    int64(len(msgs)),
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		zap.S().Errorf("[events] xpending %s: %v", key, err)
+		return nil
+	}
+	out := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		out[p.ID] = p.RetryCount
+	}
+	return out
+}
+
+// deadLetter 将消息连同来源信息写入死信 Stream 后 ack，写入失败时保留在 pending 中下次再试
+func (b *Bus) deadLetter(ctx context.Context, topic, group string, msg redis.XMessage, deliveries int64) {
+	values := make(map[string]interface{}, len(msg.Values)+3)
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+	values["source_id"] = msg.ID
+	values["group"] = group
+	values["deliveries"] = deliveries
+
+	dead := DeadLetterKey(topic)
+	if err := b.rdb.XAdd(ctx, &redis.XAddArgs{Stream: dead, MaxLen: b.maxLen, Approx: true, Values: values}).Err(); err != nil {
+		zap.S().Errorf("[events] xadd %s: %v", dead, err)
+		return
+	}
+	if err := b.rdb.XAck(ctx, StreamKey(topic), group, msg.ID).Err(); err != nil {
+		zap.S().Errorf("[events] xack %s %s: %v", topic, msg.ID, err)
+		return
+	}
+	zap.S().Warnf("[events] %s %s delivered %d times to %s, moved to %s", topic, msg.ID, deliveries, group, dead)
+}
+
+func (b *Bus) handle(ctx context.Context, topic, group string, msg redis.XMessage, h Handler) {
+	e := parseMessage(topic, msg)
+	if err := h(ctx, e); err != nil {
+		zap.S().Warnf("[events] handle %s %s (%s): %v", topic, msg.ID, e.Type, err)
+		return
+	}
+	if err := b.rdb.XAck(ctx, StreamKey(topic), group, msg.ID).Err(); err != nil {
+		zap.S().Errorf("[events] xack %s %s: %v", topic, msg.ID, err)
+	}
+}
+
+// ConsumePubSub 不论总线模式，始终通过 Pub/Sub 订阅 topic，直到 ctx 结束；
+// 用于生产者不在本仓库、只发布到 Pub/Sub 的频道（如 server:{ip}:dispatch）
+func (b *Bus) ConsumePubSub(ctx context.Context, topic string, h Handler) error {
+	sub := b.rdb.Subscribe(ctx, topic)
+	defer sub.Close()
+	zap.S().Infof("[events] subscribed %s (pubsub)", topic)
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("subscription %s closed", topic)
+			}
+			e := Event{Topic: topic, Time: time.Now().UnixMilli(), Data: json.RawMessage(msg.Payload)}
+			if err := h(ctx, e); err != nil {
+				zap.S().Warnf("[events] handle %s: %v", topic, err)
+			}
+		}
+	}
+}
+
+// Replay 按顺序重放 Stream 中 ID 在 [from, to] 内的消息（"-"/"+" 表示最早/最新），不影响消费组进度
+func (b *Bus) Replay(ctx context.Context, topic, from, to string, h Handler) error {
+	return b.replay(ctx, StreamKey(topic), topic, from, to, h)
+}
+
+// ReplayDeadLetters 与 Replay 相同，但读取 topic 的死信 Stream
+func (b *Bus) ReplayDeadLetters(ctx context.Context, topic, from, to string, h Handler) error {
+	return b.replay(ctx, DeadLetterKey(topic), topic, from, to, h)
+}
+
+func (b *Bus) replay(ctx context.Context, key, topic, from, to string, h Handler) error {
+	start := from
+	for {
+		msgs, err := b.rdb.XRangeN(ctx, key, start, to, replayBatch).Result()
+		if err != nil {
+			return fmt.Errorf("xrange %s: %w", key, err)
+		}
+		for _, msg := range msgs {
+			if err := h(ctx, parseMessage(topic, msg)); err != nil {
+				return fmt.Errorf("replay %s %s: %w", key, msg.ID, err)
+			}
+		}
+		if len(msgs) < replayBatch {
+			return nil
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+func parseMessage(topic string, msg redis.XMessage) Event {
+	e := Event{ID: msg.ID, Topic: topic}
+	if v, ok := msg.Values["type"].(string); ok {
+		e.Type = v
+	}
+	if v, ok := msg.Values["v"].(string); ok {
+		e.Version, _ = strconv.Atoi(v)
+	}
+	if v, ok := msg.Values["time"].(string); ok {
+		e.Time, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := msg.Values["data"].(string); ok {
+		e.Data = json.RawMessage(v)
+	}
+	return e
+}
@@ -0,0 +1,334 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis 在 ProcessHook 中截获命令，不连接 Redis；reply 为空时命令返回零值
+type fakeRedis struct {
+	mu    sync.Mutex
+	cmds  [][]interface{}
+	reply func(cmd redis.Cmder) error
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (f *fakeRedis) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		f.mu.Lock()
+		f.cmds = append(f.cmds, cmd.Args())
+		f.mu.Unlock()
+		if f.reply == nil {
+			return nil
+		}
+		err := f.reply(cmd)
+		if err != nil {
+			cmd.SetErr(err)
+		}
+		return err
+	}
+}
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// names 已执行命令的小写名称（XGROUP 等带子命令的取前两段）
+func (f *fakeRedis) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, 0, len(f.cmds))
+	for _, args := range f.cmds {
+		name := strings.ToLower(fmt.Sprint(args[0]))
+		if name == "xgroup" {
+			name += " " + strings.ToLower(fmt.Sprint(args[1]))
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// find 返回第一条名为 name 的命令参数
+func (f *fakeRedis) find(name string) []interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, args := range f.cmds {
+		if strings.EqualFold(fmt.Sprint(args[0]), name) {
+			return args
+		}
+	}
+	return nil
+}
+
+func newTestBus(mode string, f *fakeRedis) *Bus {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	rdb.AddHook(f)
+	return NewBus(rdb, config.EventsConfig{Mode: mode, MaxLen: 1000})
+}
+
+// hasPair 判断命令参数中是否有相邻的 key、value
+func hasPair(args []interface{}, key string, value interface{}) bool {
+	for i := 0; i+1 < len(args); i++ {
+		if fmt.Sprint(args[i]) == key && fmt.Sprint(args[i+1]) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   Event
+	}{
+		{
+			name:   "full message",
+			values: map[string]interface{}{"type": "new_position", "v": "2", "time": "1700000000000", "data": `{"coin":"BTC"}`},
+			want:   Event{ID: "1-0", Topic: "t", Type: "new_position", Version: 2, Time: 1700000000000, Data: json.RawMessage(`{"coin":"BTC"}`)},
+		},
+		{
+			name:   "missing fields",
+			values: map[string]interface{}{"data": `[]`},
+			want:   Event{ID: "1-0", Topic: "t", Data: json.RawMessage(`[]`)},
+		},
+		{
+			name:   "malformed numbers",
+			values: map[string]interface{}{"type": "x", "v": "two", "time": "now"},
+			want:   Event{ID: "1-0", Topic: "t", Type: "x"},
+		},
+		{
+			name:   "non-string values ignored",
+			values: map[string]interface{}{"type": 1, "v": 2, "time": int64(3), "data": []byte("{}")},
+			want:   Event{ID: "1-0", Topic: "t"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseMessage("t", redis.XMessage{ID: "1-0", Values: tt.values})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMessage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBusMode(t *testing.T) {
+	for mode, want := range map[string]string{
+		ModePubSub: ModePubSub, ModeStream: ModeStream, ModeBoth: ModeBoth, "": ModeBoth, "kafka": ModeBoth,
+	} {
+		if got := newTestBus(mode, &fakeRedis{}).Mode(); got != want {
+			t.Errorf("NewBus(mode=%q).Mode() = %s, want %s", mode, got, want)
+		}
+	}
+}
+
+func TestPublishModes(t *testing.T) {
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{ModePubSub, []string{"publish"}},
+		{ModeStream, []string{"xadd"}},
+		{ModeBoth, []string{"xadd", "publish"}},
+	}
+	payload := map[string]string{"coin": "BTC"}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			f := &fakeRedis{}
+			if err := newTestBus(tt.mode, f).Publish(context.Background(), "market_alert", MarketAlert, payload); err != nil {
+				t.Fatalf("Publish() error = %v", err)
+			}
+			if got := f.names(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("commands = %v, want %v", got, tt.want)
+			}
+			if args := f.find("publish"); args != nil {
+				if args[1] != "market_alert" || args[2] != `{"coin":"BTC"}` {
+					t.Errorf("publish args = %v", args)
+				}
+			}
+			if args := f.find("xadd"); args != nil {
+				if !reflect.DeepEqual(args[1:5], []interface{}{"events:market_alert", "maxlen", "~", int64(1000)}) {
+					t.Errorf("xadd stream/trim args = %v", args)
+				}
+				if !hasPair(args, "type", "market_alert") || !hasPair(args, "v", 2) || !hasPair(args, "data", `{"coin":"BTC"}`) {
+					t.Errorf("xadd values = %v", args)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishError(t *testing.T) {
+	f := &fakeRedis{reply: func(redis.Cmder) error { return errors.New("down") }}
+	err := newTestBus(ModeBoth, f).Publish(context.Background(), "t", MarketAlert, 1)
+	if err == nil || !strings.Contains(err.Error(), "xadd t") {
+		t.Fatalf("Publish() error = %v, want xadd error", err)
+	}
+	if got := f.names(); !reflect.DeepEqual(got, []string{"xadd"}) {
+		t.Errorf("commands after failed xadd = %v", got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	total := replayBatch + 3
+	msgs := make([]redis.XMessage, total)
+	for i := range msgs {
+		msgs[i] = redis.XMessage{ID: fmt.Sprintf("%d-0", i+1), Values: map[string]interface{}{"type": "x", "data": fmt.Sprint(i)}}
+	}
+	// XRANGE key start end COUNT n：start 为 "-" 或 "(id"
+	f := &fakeRedis{}
+	f.reply = func(cmd redis.Cmder) error {
+		c, ok := cmd.(*redis.XMessageSliceCmd)
+		if !ok {
+			return nil
+		}
+		args := cmd.Args()
+		from := 0
+		if s := fmt.Sprint(args[2]); strings.HasPrefix(s, "(") {
+			fmt.Sscanf(s, "(%d-0", &from)
+		}
+		c.SetVal(msgs[from:min(from+replayBatch, total)])
+		return nil
+	}
+
+	for _, dead := range []bool{false, true} {
+		f.cmds = nil
+		bus := newTestBus(ModeStream, f)
+		var got []string
+		h := func(_ context.Context, e Event) error {
+			got = append(got, string(e.Data))
+			if e.Topic != "new_positions" {
+				t.Errorf("replayed topic = %s", e.Topic)
+			}
+			return nil
+		}
+		var err error
+		if dead {
+			err = bus.ReplayDeadLetters(context.Background(), "new_positions", "-", "+", h)
+		} else {
+			err = bus.Replay(context.Background(), "new_positions", "-", "+", h)
+		}
+		if err != nil {
+			t.Fatalf("replay error = %v", err)
+		}
+		if len(got) != total || got[0] != "0" || got[total-1] != fmt.Sprint(total-1) {
+			t.Fatalf("replayed %d events, want %d in order", len(got), total)
+		}
+		wantKey := StreamKey("new_positions")
+		if dead {
+			wantKey = DeadLetterKey("new_positions")
+		}
+		f.mu.Lock()
+		if len(f.cmds) != 2 || f.cmds[0][1] != wantKey || f.cmds[1][2] != fmt.Sprintf("(%d-0", replayBatch) {
+			t.Errorf("xrange calls = %v", f.cmds)
+		}
+		f.mu.Unlock()
+	}
+
+	// 处理失败时停止重放
+	bus := newTestBus(ModeStream, f)
+	n := 0
+	err := bus.Replay(context.Background(), "t", "-", "+", func(context.Context, Event) error {
+		n++
+		return errors.New("boom")
+	})
+	if err == nil || n != 1 {
+		t.Errorf("Replay() with failing handler = %v after %d events", err, n)
+	}
+}
+
+func TestConsumeStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msg := func(id, data string) redis.XMessage {
+		return redis.XMessage{ID: id, Values: map[string]interface{}{"type": "x", "v": "1", "data": data}}
+	}
+	reads := 0
+	f := &fakeRedis{}
+	f.reply = func(cmd redis.Cmder) error {
+		switch c := cmd.(type) {
+		case *redis.XAutoClaimCmd:
+			// 空闲过久的消息：d1 已投递 6 次转入死信，r1 重新处理
+			c.SetVal([]redis.XMessage{msg("1-0", "d1"), msg("2-0", "r1")}, "0-0")
+		case *redis.XPendingExtCmd:
+			c.SetVal([]redis.XPendingExt{{ID: "1-0", RetryCount: maxDeliveries + 1}, {ID: "2-0", RetryCount: 2}})
+		case *redis.XStreamSliceCmd:
+			reads++
+			start := fmt.Sprint(cmd.Args()[len(cmd.Args())-1])
+			switch {
+			case reads == 1 && start == "0": // 本消费者的 pending
+				c.SetVal([]redis.XStream{{Stream: "events:t", Messages: []redis.XMessage{msg("3-0", "p1")}}})
+			case reads == 2 && start == "3-0":
+				c.SetVal([]redis.XStream{{Stream: "events:t"}})
+			case reads == 3 && start == ">":
+				c.SetVal([]redis.XStream{{Stream: "events:t", Messages: []redis.XMessage{msg("4-0", "n1"), msg("5-0", "fail")}}})
+			default:
+				cancel()
+				return redis.Nil
+			}
+		}
+		return nil
+	}
+
+	var handled []string
+	bus := newTestBus(ModeStream, f)
+	err := bus.Consume(ctx, "t", "g", "c1", func(_ context.Context, e Event) error {
+		handled = append(handled, string(e.Data))
+		if string(e.Data) == "fail" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Consume() error = %v, want context.Canceled", err)
+	}
+	if want := []string{"r1", "p1", "n1", "fail"}; !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled = %v, want %v", handled, want)
+	}
+
+	if args := f.find("xgroup"); args == nil || args[2] != "events:t" || args[3] != "g" || args[4] != groupStartID {
+		t.Errorf("xgroup create args = %v", args)
+	}
+	var acked []string
+	f.mu.Lock()
+	for _, args := range f.cmds {
+		if strings.EqualFold(fmt.Sprint(args[0]), "xack") {
+			acked = append(acked, fmt.Sprint(args[3]))
+		}
+	}
+	f.mu.Unlock()
+	// 死信先写入死信 Stream 再 ack；处理失败的消息不 ack
+	if want := []string{"1-0", "2-0", "3-0", "4-0"}; !reflect.DeepEqual(acked, want) {
+		t.Errorf("acked = %v, want %v", acked, want)
+	}
+	if args := f.find("xadd"); args == nil || args[1] != DeadLetterKey("t") || !hasPair(args, "source_id", "1-0") {
+		t.Errorf("dead letter xadd = %v", args)
+	}
+}
+
+func TestConsumeNonStreamUsesPubSub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f := &fakeRedis{}
+	err := newTestBus(ModeBoth, f).Consume(ctx, "t", "g", "c1", func(context.Context, Event) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Consume() error = %v, want context.Canceled", err)
+	}
+	for _, name := range f.names() {
+		if strings.HasPrefix(name, "x") {
+			t.Errorf("both mode consumer issued stream command %s", name)
+		}
+	}
+}
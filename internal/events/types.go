@@ -0,0 +1,30 @@
+package events
+
+// Type 事件类型及其结构版本；结构有不兼容变更时递增 Version
+type Type struct {
+	Name    string
+	Version int
+}
+
+// watcher
+var (
+	NewPosition             = Type{"new_position", 2} // v2: 增加交易员上下文字段
+	PositionClosed          = Type{"position_closed", 1}
+	PositionSizeChanged     = Type{"position_size_changed", 1}
+	PositionFlipped         = Type{"position_flipped", 1}
+	PositionLeverageChanged = Type{"position_leverage_changed", 1}
+	MarketAlert             = Type{"market_alert", 2} // v2: 按币种、方向统计
+)
+
+// follower
+var (
+	TrackWalletNotify = Type{"track_wallet_notify", 1}
+)
+
+// snapshot / liqalert / hotcoin
+var (
+	LabelChange           = Type{"label_change", 1}
+	SmartMoneyPositioning = Type{"smart_money_positioning", 1}
+	LiquidationAlert      = Type{"liquidation_alert", 1}
+	HotCoinUpdate         = Type{"hot_coin_update", 1}
+)
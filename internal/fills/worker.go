@@ -1,6 +1,7 @@
 package fills
 
 import (
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -161,6 +162,7 @@ func (w *Worker) saveFills(address string, fills []model.Fill) int {
 			Tid:           f.Tid,
 			Cloid:         f.Cloid,
 			FeeToken:      f.FeeToken,
+			Liquidation:   f.Liquidation != nil && strings.EqualFold(f.Liquidation.LiquidatedUser, address),
 		})
 	}
 
@@ -0,0 +1,187 @@
+package follower
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hypercopy/crawler/internal/model"
+	"go.uber.org/zap"
+)
+
+// coinConditionFields 交易员分币种条件字段 -> trader_coin_statistics 取值
+var coinConditionFields = map[string]func(s *model.TraderCoinStatistic) string{
+	"coin_trade_count":    func(s *model.TraderCoinStatistic) string { return s.TradeCount },
+	"coin_win_rate":       func(s *model.TraderCoinStatistic) string { return s.WinRate },
+	"coin_realized_pnl":   func(s *model.TraderCoinStatistic) string { return s.RealizedPnl },
+	"coin_avg_holding_ms": func(s *model.TraderCoinStatistic) string { return s.AvgHoldingMs },
+	"coin_long_count":     func(s *model.TraderCoinStatistic) string { return s.LongCount },
+	"coin_short_count":    func(s *model.TraderCoinStatistic) string { return s.ShortCount },
+	"coin_funding":        func(s *model.TraderCoinStatistic) string { return s.Funding },
+}
+
+// scoreConditionFields 交易员综合评分条件字段 -> trader_scores 取值
+var scoreConditionFields = map[string]func(s *model.TraderScore) string{
+	"score":            func(s *model.TraderScore) string { return s.Score },
+	"score_rank":       func(s *model.TraderScore) string { return strconv.Itoa(s.Rank) },
+	"score_percentile": func(s *model.TraderScore) string { return s.Percentile },
+}
+
+// 指标百分位条件字段前缀，后接 trader_statistics 列名
+const (
+	pctFieldPrefix       = "pct_"
+	bucketPctFieldPrefix = "bucket_pct_"
+)
+
+// percentileField 解析百分位条件字段，返回指标名以及是否按账户规模分组；
+// 前缀匹配即视为百分位条件，指标名是否有效由 matchTraderConditions 校验
+func percentileField(field string) (metric string, bucket bool, ok bool) {
+	if m, found := strings.CutPrefix(field, bucketPctFieldPrefix); found && m != "" {
+		return m, true, true
+	}
+	if m, found := strings.CutPrefix(field, pctFieldPrefix); found && m != "" {
+		return m, false, true
+	}
+	return "", false, false
+}
+
+// metricPeriodWindow 将 copy_trade_config.trader_metric_period 映射为统计窗口；
+// 统计表没有 90 天窗口，90d 及其他未知周期返回 false
+func metricPeriodWindow(period string) (string, bool) {
+	switch period {
+	case "1d":
+		return model.LeaderboardWindowDay, true
+	case "7d":
+		return model.LeaderboardWindowWeek, true
+	case "30d":
+		return model.LeaderboardWindowMonth, true
+	case "all", "":
+		return model.LeaderboardWindowAllTime, true
+	default:
+		return "", false
+	}
+}
+
+// matchTraderConditions 校验 TraderConditions 中与币种表现、综合评分、指标百分位相关的条件；
+// 没有币种统计记录（未交易过该币种）时币种条件一律不通过，没有评分记录（未评分交易员）时评分条件一律不通过。其他字段不在此处判断。
+func (f *Follower) matchTraderConditions(cfg model.CopyTradingConfig, addr, coin string) bool {
+	var coinConds, scoreConds, pctConds []model.Condition
+	for _, c := range cfg.TraderConditions {
+		if _, ok := coinConditionFields[c.Field]; ok {
+			coinConds = append(coinConds, c)
+		}
+		if _, ok := scoreConditionFields[c.Field]; ok {
+			scoreConds = append(scoreConds, c)
+		}
+		if metric, _, ok := percentileField(c.Field); ok {
+			if !slices.Contains(model.PercentileMetrics, metric) {
+				zap.S().Warnf("[follower] cfg=%d: unknown percentile metric in condition %q", cfg.ID, c.Field)
+				return false
+			}
+			pctConds = append(pctConds, c)
+		}
+	}
+	if len(coinConds) == 0 && len(scoreConds) == 0 && len(pctConds) == 0 {
+		return true
+	}
+	window, ok := metricPeriodWindow(cfg.TraderMetricPeriod)
+	if !ok {
+		zap.S().Warnf("[follower] cfg=%d: unsupported trader_metric_period %q for coin/score/percentile conditions",
+			cfg.ID, cfg.TraderMetricPeriod)
+		return false
+	}
+
+	if len(coinConds) > 0 {
+		var stat model.TraderCoinStatistic
+		res := f.db.Where("address = ? AND coin = ? AND \"window\" = ?", addr, coin, window).Limit(1).Find(&stat)
+		if res.Error != nil {
+			zap.S().Errorf("[follower] query coin stats: %v", res.Error)
+			return false
+		}
+		if res.RowsAffected == 0 {
+			return false
+		}
+		for _, c := range coinConds {
+			actual, _ := strconv.ParseFloat(coinConditionFields[c.Field](&stat), 64)
+			if !compare(actual, c.Operator, c.Value) {
+				return false
+			}
+		}
+	}
+
+	if len(scoreConds) > 0 {
+		var score model.TraderScore
+		res := f.db.Where("address = ? AND \"window\" = ?", addr, window).Limit(1).Find(&score)
+		if res.Error != nil {
+			zap.S().Errorf("[follower] query trader score: %v", res.Error)
+			return false
+		}
+		if res.RowsAffected == 0 {
+			return false
+		}
+		for _, c := range scoreConds {
+			actual, _ := strconv.ParseFloat(scoreConditionFields[c.Field](&score), 64)
+			if !compare(actual, c.Operator, c.Value) {
+				return false
+			}
+		}
+	}
+
+	if len(pctConds) > 0 {
+		var stat model.TraderStatistic
+		if err := f.db.Select("percentiles", "bucket_percentiles").
+			Where("address = ? AND \"window\" = ?", addr, window).
+			Limit(1).Find(&stat).Error; err != nil {
+			zap.S().Errorf("[follower] query trader percentiles: %v", err)
+			return false
+		}
+		var all, bucket map[string]float64
+		if len(stat.Percentiles) > 0 {
+			if err := json.Unmarshal(stat.Percentiles, &all); err != nil {
+				zap.S().Errorf("[follower] unmarshal percentiles %s: %v", addr, err)
+				return false
+			}
+		}
+		if len(stat.BucketPercentiles) > 0 {
+			if err := json.Unmarshal(stat.BucketPercentiles, &bucket); err != nil {
+				zap.S().Errorf("[follower] unmarshal bucket percentiles %s: %v", addr, err)
+				return false
+			}
+		}
+		for _, c := range pctConds {
+			metric, byBucket, _ := percentileField(c.Field)
+			actual := all[metric]
+			if byBucket {
+				actual = bucket[metric]
+			}
+			if !compare(actual, c.Operator, c.Value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// compare 按 Condition.Operator 比较实际值与阈值
+func compare(actual float64, operator, value string) bool {
+	expected, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return false
+	}
+	switch operator {
+	case "<":
+		return actual < expected
+	case "<=":
+		return actual <= expected
+	case ">":
+		return actual > expected
+	case ">=":
+		return actual >= expected
+	case "=":
+		return actual == expected
+	default:
+		return false
+	}
+}
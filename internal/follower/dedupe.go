@@ -0,0 +1,50 @@
+package follower
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"go.uber.org/zap"
+)
+
+const (
+	redisFillDedupePrefix = "follower:fill:" // + address:tid，值为处理该成交的服务器 IP
+	fillClaimTTL          = 2 * time.Minute  // 处理中的抢占有效期，处理中途退出时过期后可被补拉重新处理
+	fillDedupeTTL         = 24 * time.Hour   // 处理完成后的去重有效期，需大于 maxBackfillWindow，保证补拉到的成交仍能去重
+)
+
+// claimFill 以 SETNX 抢占成交的处理权：重连补拉与实时推送重复、或重新分配期间两台服务器同时订阅同一地址时，
+// 同一笔成交（tid）只处理一次。抢占只保留 fillClaimTTL，处理完成后由 confirmFill 延长；
+// 处理中途退出导致的重复处理由交易所按 cloid 拒绝重复订单。Redis 不可用时同样放行
+func (f *Follower) claimFill(ctx context.Context, addr string, fill *model.Fill) bool {
+	ok, err := f.rdb.SetNX(ctx, fillDedupeKey(addr, fill), f.serverIP, fillClaimTTL).Result()
+	if err != nil {
+		zap.S().Errorf("[follower] claim fill %s tid=%d: %v", utility.AbbrWithEllipsis(addr), fill.Tid, err)
+		return true
+	}
+	return ok
+}
+
+// confirmFill 成交处理完成后将去重 key 延长到 fillDedupeTTL
+func (f *Follower) confirmFill(ctx context.Context, addr string, fill *model.Fill) {
+	if err := f.rdb.Expire(ctx, fillDedupeKey(addr, fill), fillDedupeTTL).Err(); err != nil {
+		zap.S().Errorf("[follower] confirm fill %s tid=%d: %v", utility.AbbrWithEllipsis(addr), fill.Tid, err)
+	}
+}
+
+func fillDedupeKey(addr string, fill *model.Fill) string {
+	return fmt.Sprintf("%s%s:%d", redisFillDedupePrefix, strings.ToLower(addr), fill.Tid)
+}
+
+// copyCloid 由跟单配置ID与源成交 tid 生成确定性的客户端订单ID（16 字节，0x 开头的十六进制），
+// 同一配置重复跟同一笔成交时交易所会拒绝
+func copyCloid(cfgID, tid int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", cfgID, tid)))
+	return "0x" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,50 @@
+package follower
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+var cloidPattern = regexp.MustCompile(`^0x[0-9a-f]{32}$`)
+
+func TestCopyCloid(t *testing.T) {
+	tests := []struct {
+		cfgID, tid int64
+		want       string
+	}{
+		{1, 1, "0xd6b5915c46057bcb005f46f6433df656"},
+		{42, 123456789, "0xa8221b78afa259429050efd07c3e00ce"},
+	}
+	for _, tt := range tests {
+		got := copyCloid(tt.cfgID, tt.tid)
+		if got != tt.want {
+			t.Errorf("copyCloid(%d, %d) = %s, want %s", tt.cfgID, tt.tid, got, tt.want)
+		}
+		if !cloidPattern.MatchString(got) {
+			t.Errorf("copyCloid(%d, %d) = %s is not a 16-byte hex cloid", tt.cfgID, tt.tid, got)
+		}
+	}
+
+	// 同一配置、同一成交必须得到同一 cloid；配置或成交不同则不同（包括 "1:23" 与 "12:3" 这类拼接歧义）
+	distinct := [][2]int64{{1, 23}, {12, 3}, {1, 2}, {2, 1}, {7, 1}, {7, 2}}
+	seen := make(map[string][2]int64, len(distinct))
+	for _, p := range distinct {
+		c := copyCloid(p[0], p[1])
+		if c != copyCloid(p[0], p[1]) {
+			t.Errorf("copyCloid(%d, %d) not deterministic", p[0], p[1])
+		}
+		if prev, ok := seen[c]; ok {
+			t.Errorf("copyCloid collision: %v and %v -> %s", prev, p, c)
+		}
+		seen[c] = p
+	}
+}
+
+func TestFillDedupeKey(t *testing.T) {
+	fill := &model.Fill{Tid: 99}
+	if got, want := fillDedupeKey("0xABCdef", fill), "follower:fill:0xabcdef:99"; got != want {
+		t.Errorf("fillDedupeKey() = %s, want %s", got, want)
+	}
+}
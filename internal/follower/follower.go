@@ -14,6 +14,7 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gorilla/websocket"
 	"github.com/hypercopy/crawler/internal/consts"
+	"github.com/hypercopy/crawler/internal/events"
 	hlclient "github.com/hypercopy/crawler/internal/hyperliquid"
 	"github.com/hypercopy/crawler/internal/model"
 	"github.com/hypercopy/crawler/internal/utility"
@@ -84,9 +85,19 @@ type TrackWalletNotification struct {
 type Follower struct {
 	db       *gorm.DB
 	rdb      *redis.Client
+	bus      *events.Bus
 	hl       *hlclient.Client
 	serverIP string
 
+	maxStaleness time.Duration // 成交距今超过该时长不再跟单，0 表示不限制
+
+	lastMu   sync.Mutex
+	lastFill map[string]int64 // address -> 已处理成交的时间水位线，见 recovery.go
+
+	queueMu     sync.Mutex
+	queues      map[string][]fillBatch // 正在补拉的地址 -> 补拉完成前收到的推送
+	backfillSem chan struct{}          // 限制同时进行的补拉请求数（maxBackfills）
+
 	mu    sync.RWMutex
 	addrs map[string]bool
 
@@ -94,13 +105,19 @@ type Follower struct {
 	connMu sync.Mutex
 }
 
-func New(db *gorm.DB, rdb *redis.Client, serverIP string) *Follower {
+func New(db *gorm.DB, rdb *redis.Client, bus *events.Bus, serverIP string, maxStaleness time.Duration) *Follower {
 	return &Follower{
 		db:       db,
 		rdb:      rdb,
+		bus:      bus,
 		hl:       hlclient.NewClient(),
 		serverIP: serverIP,
 		addrs:    make(map[string]bool),
+
+		maxStaleness: maxStaleness,
+		lastFill:     make(map[string]int64),
+		queues:       make(map[string][]fillBatch),
+		backfillSem:  make(chan struct{}, maxBackfills),
 	}
 }
 
@@ -130,46 +147,53 @@ func (f *Follower) loadAddresses(ctx context.Context) {
 	zap.S().Infof("[follower] loaded %d addresses for %s", len(f.addrs), f.serverIP)
 }
 
+// listenDispatch 地址分配通知由仓库外的调度服务发布到 Pub/Sub，不随总线模式切换到 Stream
 func (f *Follower) listenDispatch(ctx context.Context) {
 	ch := "server:" + f.serverIP + ":dispatch"
-	sub := f.rdb.Subscribe(ctx, ch)
-	defer sub.Close()
-
 	zap.S().Infof("[follower] listening on %s", ch)
 
-	for msg := range sub.Channel() {
-		var n dispatchNotification
-		if err := json.Unmarshal([]byte(msg.Payload), &n); err != nil {
-			zap.S().Errorf("[follower] decode dispatch: %v", err)
-			continue
+	for {
+		if err := f.bus.ConsumePubSub(ctx, ch, f.handleDispatch); err != nil {
+			zap.S().Errorf("[follower] consume dispatch: %v, retrying in %v", err, reconnectBaseDelay)
 		}
+		time.Sleep(reconnectBaseDelay)
+	}
+}
 
-		f.mu.Lock()
-		for _, a := range n.Subscribe {
-			f.addrs[a] = true
-		}
-		for _, a := range n.Unsubscribe {
-			delete(f.addrs, a)
-		}
-		total := len(f.addrs)
-		f.mu.Unlock()
-
-		for _, a := range n.Subscribe {
-			f.wsSend(wsMsg{
-				Method:       "subscribe",
-				Subscription: &subscription{Type: "userFills", User: a},
-			})
-		}
-		for _, a := range n.Unsubscribe {
-			f.wsSend(wsMsg{
-				Method:       "unsubscribe",
-				Subscription: &subscription{Type: "userFills", User: a},
-			})
-		}
+func (f *Follower) handleDispatch(ctx context.Context, e events.Event) error {
+	var n dispatchNotification
+	if err := json.Unmarshal(e.Data, &n); err != nil {
+		// 格式错误的消息重试也无法处理，直接丢弃
+		zap.S().Errorf("[follower] decode dispatch: %v", err)
+		return nil
+	}
 
-		zap.S().Infof("[follower] dispatch: +%d -%d total=%d",
-			len(n.Subscribe), len(n.Unsubscribe), total)
+	f.mu.Lock()
+	for _, a := range n.Subscribe {
+		f.addrs[a] = true
+	}
+	for _, a := range n.Unsubscribe {
+		delete(f.addrs, a)
+	}
+	total := len(f.addrs)
+	f.mu.Unlock()
+
+	for _, a := range n.Subscribe {
+		f.wsSend(wsMsg{
+			Method:       "subscribe",
+			Subscription: &subscription{Type: "userFills", User: a},
+		})
+	}
+	for _, a := range n.Unsubscribe {
+		f.wsSend(wsMsg{
+			Method:       "unsubscribe",
+			Subscription: &subscription{Type: "userFills", User: a},
+		})
 	}
+
+	zap.S().Infof("[follower] dispatch: +%d -%d total=%d",
+		len(n.Subscribe), len(n.Unsubscribe), total)
+	return nil
 }
 
 // ---------- WebSocket ----------
@@ -273,15 +297,30 @@ func (f *Follower) handleFills(ctx context.Context, data json.RawMessage) {
 		return
 	}
 
+	// 每次（重新）订阅都会先收到快照，借此补齐断线期间漏掉的成交；补拉需要请求接口，不阻塞读循环
 	if d.IsSnapshot {
+		f.startRecovery(ctx, d.User, d.Fills, time.Now().UnixMilli())
+		return
+	}
+	if f.enqueue(d.User, fillBatch{fills: d.Fills}) {
 		return
 	}
 
-	for i := range d.Fills {
-		fill := &d.Fills[i]
+	f.processFills(ctx, d.User, d.Fills)
+}
+
+func (f *Follower) processFills(ctx context.Context, addr string, fills []model.Fill) {
+	for i := range fills {
+		fill := &fills[i]
+		if !f.claimFill(ctx, addr, fill) {
+			zap.S().Infof("[follower] skip duplicate fill %s tid=%d", utility.AbbrWithEllipsis(addr), fill.Tid)
+			continue
+		}
 		action := classifyAction(fill)
-		f.notifyTrackWallets(ctx, d.User, fill, action)
-		f.processCopyTrading(ctx, d.User, fill, action)
+		f.notifyTrackWallets(ctx, addr, fill, action)
+		f.processCopyTrading(ctx, addr, fill, action)
+		f.confirmFill(ctx, addr, fill)
+		f.markProcessed(ctx, addr, fill.Time)
 	}
 }
 
@@ -341,8 +380,7 @@ func (f *Follower) notifyTrackWallets(ctx context.Context, addr string, fill *mo
 		ClosedPnl: fill.ClosedPnl,
 		Time:      fill.Time,
 	}
-	payload, _ := json.Marshal(n)
-	if err := f.rdb.Publish(ctx, trackNotifyChannel, string(payload)).Err(); err != nil {
+	if err := f.bus.Publish(ctx, trackNotifyChannel, events.TrackWalletNotify, n); err != nil {
 		zap.S().Errorf("[follower] publish notify: %v", err)
 	} else {
 		zap.S().Infof("[follower] notified %d users %s %s %s %s@%s",
@@ -367,6 +405,9 @@ func (f *Follower) processCopyTrading(ctx context.Context, addr string, fill *mo
 		if cfg.FollowSymbol != "" && cfg.FollowSymbol != fill.Coin {
 			continue
 		}
+		if !f.matchTraderConditions(cfg, addr, fill.Coin) {
+			continue
+		}
 
 		if action == consts.NotifyActionIncrease && cfg.OptFollowupIncrease == 0 && cfg.OptPositionIncreaseOpening == 0 {
 			continue
@@ -387,6 +428,12 @@ func (f *Follower) processCopyTrading(ctx context.Context, addr string, fill *mo
 			}
 		}
 
+		if age, stale := f.isStale(fill); stale {
+			zap.S().Warnf("[follower] skip stale fill: cfg=%d %s %s age=%v", cfg.ID, fill.Coin, fill.Dir, age.Truncate(time.Second))
+			f.saveRecord(cfg, fill, consts.ExecStatusSkipped, fmt.Sprintf("fill too old: %v", age.Truncate(time.Second)))
+			continue
+		}
+
 		f.executeCopyOrder(ctx, cfg, addr, fill, action)
 	}
 }
@@ -414,6 +461,7 @@ func (f *Follower) executeCopyOrder(ctx context.Context, cfg model.CopyTradingCo
 		return
 	}
 
+	cloid := copyCloid(cfg.ID, fill.Tid)
 	req := hyperliquid.CreateOrderRequest{
 		Coin:       fill.Coin,
 		IsBuy:      isBuy,
@@ -423,6 +471,7 @@ func (f *Follower) executeCopyOrder(ctx context.Context, cfg model.CopyTradingCo
 		OrderType: hyperliquid.OrderType{
 			Limit: &hyperliquid.LimitOrderType{Tif: hyperliquid.TifIoc},
 		},
+		ClientOrderID: &cloid,
 	}
 
 	status, err := exchange.Order(ctx, req, nil)
@@ -439,8 +488,8 @@ func (f *Follower) executeCopyOrder(ctx context.Context, cfg model.CopyTradingCo
 		return
 	}
 
-	zap.S().Infof("[follower] order placed: user=%d cfg=%d %s %.6f %s@%.2f",
-		cfg.UserID, cfg.ID, fill.Coin, orderSize, boolToSide(isBuy), orderPrice)
+	zap.S().Infof("[follower] order placed: user=%d cfg=%d %s %.6f %s@%.2f cloid=%s",
+		cfg.UserID, cfg.ID, fill.Coin, orderSize, boolToSide(isBuy), orderPrice, cloid)
 	f.saveCopyTradingAndRecord(cfg, addr, fill, action, model.CopyTradingStatusFollowing, consts.ExecStatusSuccess, "")
 }
 
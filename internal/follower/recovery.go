@@ -0,0 +1,186 @@
+package follower
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	redisKeyLastFill  = "follower:last_fill_time" // HASH，address -> 最近一笔已处理成交的时间（毫秒时间戳）
+	maxBackfillWindow = time.Hour                 // 补拉的最长时间范围，更早的缺口直接放弃
+	maxBackfills      = 2                         // 同时进行的 userFillsByTime 请求数；重连后所有地址同时补拉，其余排队等待
+)
+
+// fillBatch 地址补拉期间收到的一次 userFills 推送
+type fillBatch struct {
+	fills      []model.Fill
+	snapshot   bool
+	receivedAt int64
+}
+
+// startRecovery 处理订阅后收到的 userFills 快照。水位线在收到快照时同步读取；需要补拉时在后台请求接口，
+// 补拉完成前该地址的实时推送先排队，补拉结束后按到达顺序处理，保证同一地址的成交串行、按时间处理
+func (f *Follower) startRecovery(ctx context.Context, addr string, snapshot []model.Fill, receivedAt int64) {
+	if f.enqueue(addr, fillBatch{fills: snapshot, snapshot: true, receivedAt: receivedAt}) {
+		return
+	}
+	last, err := f.lastFillTime(ctx, addr)
+	if err != nil {
+		zap.S().Errorf("[follower] load last fill time %s: %v", utility.AbbrWithEllipsis(addr), err)
+		return
+	}
+	if last == 0 {
+		f.recoverGap(ctx, addr, snapshot, receivedAt, 0)
+		return
+	}
+
+	f.queueMu.Lock()
+	f.queues[addr] = []fillBatch{}
+	f.queueMu.Unlock()
+	go f.drain(ctx, addr, snapshot, receivedAt, last)
+}
+
+// takeQueued 取出地址补拉期间排队的推送；队列为空时结束补拉状态并返回 nil，之后的推送直接处理
+func (f *Follower) takeQueued(addr string) []fillBatch {
+	f.queueMu.Lock()
+	defer f.queueMu.Unlock()
+	q := f.queues[addr]
+	if len(q) == 0 {
+		delete(f.queues, addr)
+		return nil
+	}
+	f.queues[addr] = []fillBatch{}
+	return q
+}
+
+// enqueue 地址正在补拉时将推送排队并返回 true
+func (f *Follower) enqueue(addr string, b fillBatch) bool {
+	f.queueMu.Lock()
+	defer f.queueMu.Unlock()
+	q, ok := f.queues[addr]
+	if ok {
+		f.queues[addr] = append(q, b)
+	}
+	return ok
+}
+
+// drain 执行补拉，然后处理补拉期间排队的推送，队列清空后恢复实时处理
+func (f *Follower) drain(ctx context.Context, addr string, snapshot []model.Fill, receivedAt, last int64) {
+	f.recoverGap(ctx, addr, snapshot, receivedAt, last)
+	for {
+		q := f.takeQueued(addr)
+		if q == nil {
+			return
+		}
+		for _, b := range q {
+			if !b.snapshot {
+				f.processFills(ctx, addr, b.fills)
+				continue
+			}
+			last, err := f.lastFillTime(ctx, addr)
+			if err != nil {
+				zap.S().Errorf("[follower] load last fill time %s: %v", utility.AbbrWithEllipsis(addr), err)
+				continue
+			}
+			f.recoverGap(ctx, addr, b.fills, b.receivedAt, last)
+		}
+	}
+}
+
+// recoverGap 用 userFillsByTime 补拉水位线 last 之后、收到快照之前漏掉的成交（之后的成交由实时推送处理），
+// 接口失败时退回到与快照比对。从 last 开始（含）拉取，与 last 同一毫秒的其他成交不会漏掉，已处理的由 tid 去重。
+// 同时进行的补拉请求不超过 maxBackfills 个。last 为 0（地址第一次被本服务处理）时只记录水位线，不回放历史成交
+func (f *Follower) recoverGap(ctx context.Context, addr string, snapshot []model.Fill, receivedAt, last int64) {
+	if last == 0 {
+		for i := range snapshot {
+			f.markProcessed(ctx, addr, snapshot[i].Time)
+		}
+		return
+	}
+
+	start := max(last, receivedAt-maxBackfillWindow.Milliseconds())
+	f.backfillSem <- struct{}{}
+	fills, err := f.hl.FetchUserFillsByTime(addr, start, receivedAt)
+	<-f.backfillSem
+	if err != nil {
+		zap.S().Warnf("[follower] backfill %s: %v, falling back to snapshot", utility.AbbrWithEllipsis(addr), err)
+		fills = snapshot
+	}
+
+	missed := missedFills(fills, start, receivedAt)
+	if len(missed) == 0 {
+		return
+	}
+	zap.S().Infof("[follower] backfill %s: %d fills since %s",
+		utility.AbbrWithEllipsis(addr), len(missed), time.UnixMilli(last).Format(time.DateTime))
+	f.processFills(ctx, addr, missed)
+}
+
+// missedFills 返回时间在 [start, receivedAt] 内的成交，按时间升序，同一时间保持原顺序
+func missedFills(fills []model.Fill, start, receivedAt int64) []model.Fill {
+	missed := make([]model.Fill, 0, len(fills))
+	for _, fill := range fills {
+		if fill.Time >= start && fill.Time <= receivedAt {
+			missed = append(missed, fill)
+		}
+	}
+	sort.SliceStable(missed, func(i, j int) bool {
+		return missed[i].Time < missed[j].Time
+	})
+	return missed
+}
+
+// lastFillTime 优先取内存中的水位线，没有时从 Redis 加载；从未处理过返回 0
+func (f *Follower) lastFillTime(ctx context.Context, addr string) (int64, error) {
+	f.lastMu.Lock()
+	last, ok := f.lastFill[addr]
+	f.lastMu.Unlock()
+	if ok {
+		return last, nil
+	}
+
+	v, err := f.rdb.HGet(ctx, redisKeyLastFill, addr).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	last, _ = strconv.ParseInt(v, 10, 64)
+
+	f.lastMu.Lock()
+	if cur := f.lastFill[addr]; cur > last {
+		last = cur
+	}
+	f.lastFill[addr] = last
+	f.lastMu.Unlock()
+	return last, nil
+}
+
+// markProcessed 推进地址的成交水位线，只前进不后退
+func (f *Follower) markProcessed(ctx context.Context, addr string, fillTime int64) {
+	f.lastMu.Lock()
+	if fillTime <= f.lastFill[addr] {
+		f.lastMu.Unlock()
+		return
+	}
+	f.lastFill[addr] = fillTime
+	f.lastMu.Unlock()
+
+	if err := f.rdb.HSet(ctx, redisKeyLastFill, addr, fillTime).Err(); err != nil {
+		zap.S().Errorf("[follower] save last fill time %s: %v", utility.AbbrWithEllipsis(addr), err)
+	}
+}
+
+// isStale 成交距今超过 maxStaleness 时不再跟单（0 表示不限制）
+func (f *Follower) isStale(fill *model.Fill) (time.Duration, bool) {
+	age := time.Since(time.UnixMilli(fill.Time))
+	return age, f.maxStaleness > 0 && age > f.maxStaleness
+}
@@ -0,0 +1,93 @@
+package follower
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+func TestMissedFills(t *testing.T) {
+	fill := func(tid, ms int64) model.Fill { return model.Fill{Tid: tid, Time: ms} }
+	tests := []struct {
+		name  string
+		fills []model.Fill
+		start int64
+		recv  int64
+		want  []int64 // tid
+	}{
+		{"empty", nil, 100, 200, []int64{}},
+		{"window bounds inclusive", []model.Fill{fill(1, 99), fill(2, 100), fill(3, 150), fill(4, 200), fill(5, 201)}, 100, 200, []int64{2, 3, 4}},
+		{"sorted by time", []model.Fill{fill(1, 180), fill(2, 120), fill(3, 150)}, 100, 200, []int64{2, 3, 1}},
+		{"same millisecond keeps order", []model.Fill{fill(1, 150), fill(2, 120), fill(3, 150), fill(4, 150)}, 100, 200, []int64{2, 1, 3, 4}},
+		{"all outside", []model.Fill{fill(1, 50), fill(2, 250)}, 100, 200, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]int64, 0)
+			for _, f := range missedFills(tt.fills, tt.start, tt.recv) {
+				got = append(got, f.Tid)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missedFills() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackfillQueue(t *testing.T) {
+	f := &Follower{queues: make(map[string][]fillBatch)}
+	batch := func(tid int64) fillBatch { return fillBatch{fills: []model.Fill{{Tid: tid}}} }
+	tids := func(q []fillBatch) []int64 {
+		var out []int64
+		for _, b := range q {
+			out = append(out, b.fills[0].Tid)
+		}
+		return out
+	}
+
+	if f.enqueue("a", batch(1)) {
+		t.Fatal("enqueue() queued a push for an address that is not backfilling")
+	}
+
+	// 补拉开始后推送按到达顺序排队，其他地址不受影响
+	f.queues["a"] = []fillBatch{}
+	for _, tid := range []int64{1, 2, 3} {
+		if !f.enqueue("a", batch(tid)) {
+			t.Fatalf("enqueue(%d) not queued during backfill", tid)
+		}
+	}
+	if f.enqueue("b", batch(9)) {
+		t.Error("enqueue() queued a push for another address")
+	}
+	// 补拉期间再次收到的快照也排队，由 drain 按顺序重新补拉
+	f.startRecovery(context.Background(), "a", []model.Fill{{Tid: 4}}, 1000)
+
+	q := f.takeQueued("a")
+	if got := tids(q); !reflect.DeepEqual(got, []int64{1, 2, 3, 4}) {
+		t.Fatalf("takeQueued() = %v, want [1 2 3 4]", got)
+	}
+	if !q[3].snapshot || q[3].receivedAt != 1000 || q[0].snapshot {
+		t.Errorf("queued snapshot flags = %+v", q)
+	}
+
+	// 处理排队推送期间到达的推送继续排队，下一轮取出
+	if !f.enqueue("a", batch(5)) {
+		t.Fatal("enqueue() not queued while draining")
+	}
+	if got := tids(f.takeQueued("a")); !reflect.DeepEqual(got, []int64{5}) {
+		t.Fatalf("second takeQueued() = %v, want [5]", got)
+	}
+
+	// 队列清空后结束补拉，之后的推送直接处理
+	if q := f.takeQueued("a"); q != nil {
+		t.Fatalf("takeQueued() on empty queue = %v, want nil", q)
+	}
+	if _, ok := f.queues["a"]; ok {
+		t.Error("queue not removed after drain")
+	}
+	if f.enqueue("a", batch(6)) {
+		t.Error("enqueue() still queuing after backfill finished")
+	}
+}
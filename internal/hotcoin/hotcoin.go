@@ -0,0 +1,190 @@
+package hotcoin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/events"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	redisTopKey    = "hot_coin:top"
+	redisChannel   = "hot_coin_update"
+	historyBatch   = 500
+	historyMaxDays = 180
+)
+
+// coinAgg 单个币种的持仓聚合
+type coinAgg struct {
+	Coin          string
+	HolderCount   int
+	LongHolders   int
+	ShortHolders  int
+	TotalNotional float64
+	LongNotional  float64
+	ShortNotional float64
+}
+
+// Job 周期性按 trader_positions 聚合各币种的持仓交易员数与名义价值，写入 hot_coin / hot_coin_history 并推送 Redis
+type Job struct {
+	db       *gorm.DB
+	rdb      *redis.Client
+	bus      *events.Bus
+	interval time.Duration
+	top      int
+}
+
+func New(db *gorm.DB, rdb *redis.Client, bus *events.Bus, interval time.Duration, top int) *Job {
+	return &Job{
+		db:       db,
+		rdb:      rdb,
+		bus:      bus,
+		interval: interval,
+		top:      top,
+	}
+}
+
+func (j *Job) Run() {
+	for round := 1; ; round++ {
+		if err := j.runOnce(context.Background()); err != nil {
+			zap.S().Errorf("[hotcoin] round %d error: %v", round, err)
+		}
+		time.Sleep(j.interval)
+	}
+}
+
+func (j *Job) runOnce(ctx context.Context) error {
+	var aggs []coinAgg
+	err := j.db.Raw(`
+		SELECT coin,
+			COUNT(DISTINCT address) AS holder_count,
+			COUNT(DISTINCT address) FILTER (WHERE szi > 0) AS long_holders,
+			COUNT(DISTINCT address) FILTER (WHERE szi < 0) AS short_holders,
+			COALESCE(SUM(ABS(position_value)), 0) AS total_notional,
+			COALESCE(SUM(ABS(position_value)) FILTER (WHERE szi > 0), 0) AS long_notional,
+			COALESCE(SUM(ABS(position_value)) FILTER (WHERE szi < 0), 0) AS short_notional
+		FROM trader_positions
+		WHERE szi <> 0
+		GROUP BY coin
+		ORDER BY holder_count DESC, total_notional DESC, coin`).Scan(&aggs).Error
+	if err != nil {
+		return fmt.Errorf("aggregate positions: %w", err)
+	}
+
+	var previous []model.HotCoin
+	if err := j.db.Find(&previous).Error; err != nil {
+		return fmt.Errorf("load previous hot coins: %w", err)
+	}
+	prev := make(map[string]model.HotCoin, len(previous))
+	for _, p := range previous {
+		prev[p.Coin] = p
+	}
+
+	now := time.Now()
+	coins := make([]string, 0, len(aggs))
+	records := make([]model.HotCoin, 0, len(aggs))
+	history := make([]model.HotCoinHistory, 0, len(aggs))
+	for i, a := range aggs {
+		rank := i + 1
+		hc := model.HotCoin{
+			Coin:           a.Coin,
+			Rank:           rank,
+			HolderCount:    a.HolderCount,
+			LongHolders:    a.LongHolders,
+			ShortHolders:   a.ShortHolders,
+			TotalNotional:  utility.FmtFloat(a.TotalNotional),
+			LongNotional:   utility.FmtFloat(a.LongNotional),
+			ShortNotional:  utility.FmtFloat(a.ShortNotional),
+			HolderChange:   a.HolderCount,
+			NotionalChange: utility.FmtFloat(a.TotalNotional),
+			UpdatedAt:      now,
+		}
+		if p, ok := prev[a.Coin]; ok {
+			prevNotional, _ := strconv.ParseFloat(p.TotalNotional, 64)
+			hc.HolderChange = a.HolderCount - p.HolderCount
+			hc.NotionalChange = utility.FmtFloat(a.TotalNotional - prevNotional)
+			if p.Rank > 0 {
+				hc.RankChange = p.Rank - rank
+			}
+		}
+		coins = append(coins, a.Coin)
+		records = append(records, hc)
+		history = append(history, model.HotCoinHistory{
+			Coin:          a.Coin,
+			Time:          now.UnixMilli(),
+			Rank:          rank,
+			HolderCount:   a.HolderCount,
+			LongHolders:   a.LongHolders,
+			ShortHolders:  a.ShortHolders,
+			TotalNotional: hc.TotalNotional,
+			LongNotional:  hc.LongNotional,
+			ShortNotional: hc.ShortNotional,
+		})
+	}
+
+	err = j.db.Transaction(func(tx *gorm.DB) error {
+		// 已无人持仓的币种移出榜单
+		del := tx.Model(&model.HotCoin{})
+		if len(coins) > 0 {
+			del = del.Where("coin NOT IN ?", coins)
+		} else {
+			del = del.Where("1 = 1")
+		}
+		if err := del.Delete(&model.HotCoin{}).Error; err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "coin"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"rank", "holder_count", "long_holders", "short_holders",
+				"total_notional", "long_notional", "short_notional",
+				"holder_change", "notional_change", "rank_change", "updated_at",
+			}),
+		}).CreateInBatches(records, historyBatch).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(history, historyBatch).Error
+	})
+	if err != nil {
+		return fmt.Errorf("save hot coins: %w", err)
+	}
+
+	cutoff := now.AddDate(0, 0, -historyMaxDays).UnixMilli()
+	if err := j.db.Where("time < ?", cutoff).Delete(&model.HotCoinHistory{}).Error; err != nil {
+		zap.S().Warnf("[hotcoin] prune history error: %v", err)
+	}
+
+	if err := j.publishTop(ctx, records); err != nil {
+		return fmt.Errorf("publish top list: %w", err)
+	}
+	zap.S().Infof("[hotcoin] ranked %d coins", len(records))
+	return nil
+}
+
+// publishTop 将前 N 个热门币种写入 Redis 并发布更新
+func (j *Job) publishTop(ctx context.Context, records []model.HotCoin) error {
+	top := records
+	if j.top > 0 && len(top) > j.top {
+		top = top[:j.top]
+	}
+	payload, err := json.Marshal(top)
+	if err != nil {
+		return err
+	}
+	if err := j.rdb.Set(ctx, redisTopKey, payload, 0).Err(); err != nil {
+		return err
+	}
+	return j.bus.Publish(ctx, redisChannel, events.HotCoinUpdate, top)
+}
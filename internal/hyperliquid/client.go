@@ -289,3 +289,51 @@ func (c *Client) FetchSpotClearinghouseState(address string) (*model.SpotClearin
 	}
 	return &result, nil
 }
+
+// --- MetaAndAssetCtxs (永续合约元数据 + 行情上下文) ---
+
+func (c *Client) FetchMetaAndAssetCtxs() (*model.PerpMeta, []model.AssetCtx, error) {
+	payload, _ := json.Marshal(map[string]string{"type": "metaAndAssetCtxs"})
+
+	body, err := c.postInfoWithRetry(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch metaAndAssetCtxs: %w", err)
+	}
+
+	// 返回 [meta, [assetCtx...]]
+	var raw [2]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal metaAndAssetCtxs: %w", err)
+	}
+	var meta model.PerpMeta
+	if err := json.Unmarshal(raw[0], &meta); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal meta: %w", err)
+	}
+	var ctxs []model.AssetCtx
+	if err := json.Unmarshal(raw[1], &ctxs); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal asset ctxs: %w", err)
+	}
+	return &meta, ctxs, nil
+}
+
+// --- L2Book (盘口，每侧最多 20 档) ---
+
+// FetchL2Book nSigFigs 为价格聚合的有效数字位数（2~5），0 表示不聚合
+func (c *Client) FetchL2Book(coin string, nSigFigs int) (*model.L2Book, error) {
+	req := map[string]interface{}{"type": "l2Book", "coin": coin}
+	if nSigFigs > 0 {
+		req["nSigFigs"] = nSigFigs
+	}
+	payload, _ := json.Marshal(req)
+
+	body, err := c.postInfoWithRetry(payload)
+	if err != nil {
+		return nil, fmt.Errorf("fetch l2Book %s: %w", coin, err)
+	}
+
+	var result model.L2Book
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal l2Book %s: %w", coin, err)
+	}
+	return &result, nil
+}
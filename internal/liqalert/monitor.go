@@ -0,0 +1,450 @@
+package liqalert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypercopy/crawler/internal/events"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	wsURL        = "wss://api.hyperliquid.xyz/ws"
+	alertChannel = "liquidation_alert"
+	cooldownKey  = "liqalert:cooldown:%s:%s:%s" // address, coin, threshold
+
+	pingInterval       = 30 * time.Second
+	reconnectBaseDelay = 3 * time.Second
+	reconnectMaxDelay  = 60 * time.Second
+
+	alertQueueSize = 256
+)
+
+type wsMsg struct {
+	Method       string        `json:"method"`
+	Subscription *subscription `json:"subscription,omitempty"`
+}
+
+type subscription struct {
+	Type string `json:"type"`
+}
+
+type wsResponse struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type allMidsData struct {
+	Mids map[string]string `json:"mids"`
+}
+
+// LiquidationAlert 发布到 Redis 的强平临近告警
+type LiquidationAlert struct {
+	Address       string  `json:"address"`
+	Coin          string  `json:"coin"`
+	Side          string  `json:"side"` // long / short
+	Szi           string  `json:"szi"`
+	Leverage      int     `json:"leverage"`
+	MidPx         float64 `json:"midPx"`
+	LiquidationPx float64 `json:"liquidationPx"`
+	DistancePct   float64 `json:"distancePct"` // 当前中间价距清算价还需不利波动的百分比
+	ThresholdPct  float64 `json:"thresholdPct"`
+	CopyUsers     []int64 `json:"copyUsers"`  // 跟单该交易员的用户
+	TrackUsers    []int64 `json:"trackUsers"` // 跟踪该交易员并开启通知的用户
+	Time          int64   `json:"time"`
+}
+
+// watchedPosition 缓存的持仓（只保留有清算价的）
+type watchedPosition struct {
+	address  string
+	coin     string
+	szi      string
+	long     bool
+	leverage int
+	liqPx    float64
+}
+
+// audience 关注某个交易员的用户
+type audience struct {
+	copyUsers  []int64
+	trackUsers []int64
+}
+
+// pendingAlert 已越过阈值、待发送的告警
+type pendingAlert struct {
+	p         watchedPosition
+	mid       float64
+	dist      float64
+	threshold float64
+	aud       audience
+}
+
+// Monitor 订阅 allMids，按缓存的持仓清算价计算距离，越过阈值时告警
+type Monitor struct {
+	db         *gorm.DB
+	rdb        *redis.Client
+	bus        *events.Bus
+	thresholds []float64 // 升序，如 [2, 5, 10]
+	cooldown   time.Duration
+	refresh    time.Duration
+
+	mu        sync.RWMutex
+	positions map[string][]watchedPosition // coin -> positions
+	audiences map[string]audience          // lower(address) -> users
+
+	alerts chan pendingAlert // check 投递、alertLoop 发送，Redis/DB 读写不占用 WS 读循环
+}
+
+func New(db *gorm.DB, rdb *redis.Client, bus *events.Bus, thresholds []float64, cooldown, refresh time.Duration) *Monitor {
+	sorted := append([]float64(nil), thresholds...)
+	sort.Float64s(sorted)
+	return &Monitor{
+		db:         db,
+		rdb:        rdb,
+		bus:        bus,
+		thresholds: sorted,
+		cooldown:   cooldown,
+		refresh:    refresh,
+		positions:  make(map[string][]watchedPosition),
+		audiences:  make(map[string]audience),
+		alerts:     make(chan pendingAlert, alertQueueSize),
+	}
+}
+
+func (m *Monitor) Run() {
+	ctx := context.Background()
+	m.reload()
+	go m.alertLoop(ctx)
+	go func() {
+		t := time.NewTicker(m.refresh)
+		defer t.Stop()
+		for range t.C {
+			m.reload()
+		}
+	}()
+	m.wsLoop()
+}
+
+// ---------- position cache ----------
+
+// reload 重新加载跟单/跟踪的交易员及其持仓
+func (m *Monitor) reload() {
+	audiences, err := m.loadAudiences()
+	if err != nil {
+		zap.S().Errorf("[liqalert] load audiences error: %v", err)
+		return
+	}
+	if len(audiences) == 0 {
+		m.mu.Lock()
+		m.positions = make(map[string][]watchedPosition)
+		m.audiences = audiences
+		m.mu.Unlock()
+		return
+	}
+
+	addrs := make([]string, 0, len(audiences))
+	for a := range audiences {
+		addrs = append(addrs, a)
+	}
+	var rows []model.TraderPosition
+	if err := m.db.Where("lower(address) IN ?", addrs).Find(&rows).Error; err != nil {
+		zap.S().Errorf("[liqalert] load positions error: %v", err)
+		return
+	}
+
+	positions := make(map[string][]watchedPosition)
+	watched := 0
+	for _, p := range rows {
+		liq, err := strconv.ParseFloat(p.LiquidationPx, 64)
+		if err != nil || liq <= 0 {
+			continue
+		}
+		szi, _ := strconv.ParseFloat(p.Szi, 64)
+		if szi == 0 {
+			continue
+		}
+		positions[p.Coin] = append(positions[p.Coin], watchedPosition{
+			address:  p.Address,
+			coin:     p.Coin,
+			szi:      p.Szi,
+			long:     szi > 0,
+			leverage: p.Leverage,
+			liqPx:    liq,
+		})
+		watched++
+	}
+
+	m.mu.Lock()
+	m.positions = positions
+	m.audiences = audiences
+	m.mu.Unlock()
+	zap.S().Infof("[liqalert] watching %d positions of %d traders", watched, len(audiences))
+}
+
+func (m *Monitor) loadAudiences() (map[string]audience, error) {
+	out := make(map[string]audience)
+
+	var configs []model.CopyTradingConfig
+	if err := m.db.Select("user_id", "target_wallet").Where("status = 1").Find(&configs).Error; err != nil {
+		return nil, fmt.Errorf("copy configs: %w", err)
+	}
+	for _, c := range configs {
+		key := strings.ToLower(c.TargetWallet)
+		a := out[key]
+		a.copyUsers = appendUnique(a.copyUsers, c.UserID)
+		out[key] = a
+	}
+
+	var wallets []model.MyTrackWallet
+	if err := m.db.Select("user_id", "wallet").Where("status = 1 AND enable_notify = 1").Find(&wallets).Error; err != nil {
+		return nil, fmt.Errorf("track wallets: %w", err)
+	}
+	for _, w := range wallets {
+		key := strings.ToLower(w.Wallet)
+		a := out[key]
+		a.trackUsers = appendUnique(a.trackUsers, w.UserID)
+		out[key] = a
+	}
+	return out, nil
+}
+
+func appendUnique(ids []int64, id int64) []int64 {
+	for _, v := range ids {
+		if v == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+// ---------- WebSocket ----------
+
+func (m *Monitor) wsLoop() {
+	delay := reconnectBaseDelay
+	for {
+		if err := m.connectAndServe(); err != nil {
+			zap.S().Errorf("[liqalert] ws error: %v, reconnect in %v", err, delay)
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+func (m *Monitor) connectAndServe() error {
+	c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer c.Close()
+
+	var writeMu sync.Mutex
+	send := func(msg wsMsg) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return c.WriteJSON(msg)
+	}
+
+	if err := send(wsMsg{Method: "subscribe", Subscription: &subscription{Type: "allMids"}}); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	zap.S().Info("[liqalert] ws connected, subscribed allMids")
+
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(pingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if err := send(wsMsg{Method: "ping"}); err != nil {
+					zap.S().Warnf("[liqalert] ws ping: %v", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	defer close(done)
+
+	for {
+		_, raw, err := c.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+
+		var resp wsResponse
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			continue
+		}
+		if resp.Channel != "allMids" {
+			continue
+		}
+
+		var d allMidsData
+		if err := json.Unmarshal(resp.Data, &d); err != nil {
+			zap.S().Errorf("[liqalert] decode allMids: %v", err)
+			continue
+		}
+		m.check(d.Mids)
+	}
+}
+
+// ---------- alerting ----------
+
+// distancePct 中间价距清算价还需不利波动的百分比，已越过清算价时为负
+func distancePct(p watchedPosition, mid float64) float64 {
+	if p.long {
+		return (mid - p.liqPx) / mid * 100
+	}
+	return (p.liqPx - mid) / mid * 100
+}
+
+// threshold 返回距离落入的最小阈值；未进入任何阈值时返回 false。
+// 距离 <= 0 说明中间价已越过缓存的清算价（仓位已被强平或清算价已变化，等待下次 reload），不告警
+func (m *Monitor) threshold(dist float64) (float64, bool) {
+	if dist <= 0 {
+		return 0, false
+	}
+	for _, t := range m.thresholds {
+		if dist <= t {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// check 在读锁内找出越过阈值的持仓，释放锁后交给 alertLoop 发送；队列满时丢弃，
+// 冷却键尚未写入，下一次 allMids 推送会重新检测到
+func (m *Monitor) check(mids map[string]string) {
+	var pending []pendingAlert
+	m.mu.RLock()
+	for coin, positions := range m.positions {
+		midStr, ok := mids[coin]
+		if !ok {
+			continue
+		}
+		mid, err := strconv.ParseFloat(midStr, 64)
+		if err != nil || mid <= 0 {
+			continue
+		}
+		for _, p := range positions {
+			dist := distancePct(p, mid)
+			t, ok := m.threshold(dist)
+			if !ok {
+				continue
+			}
+			pending = append(pending, pendingAlert{p: p, mid: mid, dist: dist, threshold: t, aud: m.audiences[strings.ToLower(p.address)]})
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, a := range pending {
+		select {
+		case m.alerts <- a:
+		default:
+			zap.S().Warnf("[liqalert] alert queue full, drop %s %s", utility.AbbrWithEllipsis(a.p.address), a.p.coin)
+		}
+	}
+}
+
+func (m *Monitor) alertLoop(ctx context.Context) {
+	for a := range m.alerts {
+		m.alert(ctx, a.p, a.mid, a.dist, a.threshold, a.aud)
+	}
+}
+
+func (m *Monitor) alert(ctx context.Context, p watchedPosition, mid, dist, threshold float64, aud audience) {
+	tKey := strconv.FormatFloat(threshold, 'f', -1, 64)
+	key := fmt.Sprintf(cooldownKey, strings.ToLower(p.address), p.coin, tKey)
+	ok, err := m.rdb.SetNX(ctx, key, 1, m.cooldown).Result()
+	if err != nil {
+		zap.S().Errorf("[liqalert] cooldown setnx: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	side := "long"
+	if !p.long {
+		side = "short"
+	}
+	a := LiquidationAlert{
+		Address:       p.address,
+		Coin:          p.coin,
+		Side:          side,
+		Szi:           p.szi,
+		Leverage:      p.leverage,
+		MidPx:         mid,
+		LiquidationPx: p.liqPx,
+		DistancePct:   dist,
+		ThresholdPct:  threshold,
+		CopyUsers:     aud.copyUsers,
+		TrackUsers:    aud.trackUsers,
+		Time:          time.Now().UnixMilli(),
+	}
+	if err := m.bus.Publish(ctx, alertChannel, events.LiquidationAlert, a); err != nil {
+		zap.S().Errorf("[liqalert] publish alert: %v", err)
+	}
+
+	if err := m.saveNotifications(a, threshold == m.thresholds[0]); err != nil {
+		zap.S().Errorf("[liqalert] save notifications: %v", err)
+	}
+	zap.S().Infof("[liqalert] %s %s %s %.2f%% from liquidation (mid=%s liq=%s, threshold %s%%), %d copy / %d track users",
+		utility.AbbrWithEllipsis(p.address), p.coin, side, dist,
+		utility.FmtFloat(mid), utility.FmtFloat(p.liqPx), tKey, len(a.CopyUsers), len(a.TrackUsers))
+}
+
+// saveNotifications 为跟单、跟踪该交易员的用户各写一条通知；最小阈值为紧急级别，其余为重要
+func (m *Monitor) saveNotifications(a LiquidationAlert, urgent bool) error {
+	level := int16(1)
+	if urgent {
+		level = 2
+	}
+	direction := "多"
+	if a.Side == "short" {
+		direction = "空"
+	}
+	title := fmt.Sprintf("交易员 %s 的 %s %s仓接近强平", utility.AbbrWithEllipsis(a.Address), a.Coin, direction)
+	content := fmt.Sprintf("%s %s仓（%dx）当前价格 %s，强平价 %s，距强平仅 %.2f%%。",
+		a.Coin, direction, a.Leverage, utility.FmtFloat(a.MidPx), utility.FmtFloat(a.LiquidationPx), a.DistancePct)
+
+	var rows []model.Notification
+	for _, uid := range a.CopyUsers {
+		rows = append(rows, model.Notification{
+			UserID:   uid,
+			Category: "copy_trading",
+			Title:    title,
+			Content:  content,
+			RefType:  "position",
+			Level:    level,
+			Status:   1,
+		})
+	}
+	for _, uid := range a.TrackUsers {
+		rows = append(rows, model.Notification{
+			UserID:   uid,
+			Category: "track",
+			Title:    title,
+			Content:  content,
+			RefType:  "track_wallet",
+			Level:    level,
+			Status:   1,
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return m.db.Create(&rows).Error
+}
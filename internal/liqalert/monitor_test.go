@@ -0,0 +1,97 @@
+package liqalert
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDistancePct(t *testing.T) {
+	tests := []struct {
+		name string
+		p    watchedPosition
+		mid  float64
+		want float64
+	}{
+		{"long above liquidation", watchedPosition{long: true, liqPx: 90}, 100, 10},
+		{"short below liquidation", watchedPosition{long: false, liqPx: 105}, 100, 5},
+		{"long at liquidation", watchedPosition{long: true, liqPx: 100}, 100, 0},
+		{"long crossed", watchedPosition{long: true, liqPx: 110}, 100, -10},
+		{"short crossed", watchedPosition{long: false, liqPx: 95}, 100, -5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := distancePct(tt.p, tt.mid); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("distancePct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	m := New(nil, nil, nil, []float64{10, 2, 5}, time.Minute, time.Minute)
+	tests := []struct {
+		dist   float64
+		want   float64
+		wantOK bool
+	}{
+		{1, 2, true},
+		{2, 2, true},
+		{2.1, 5, true},
+		{7, 10, true},
+		{10, 10, true},
+		{10.5, 0, false},
+		{0, 0, false},
+		{-3, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := m.threshold(tt.dist)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("threshold(%v) = (%v, %v), want (%v, %v)", tt.dist, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNewSortsThresholds(t *testing.T) {
+	tests := []struct {
+		in   []float64
+		want []float64
+	}{
+		{[]float64{10, 2, 5}, []float64{2, 5, 10}},
+		{[]float64{1}, []float64{1}},
+		{[]float64{3, 3, 1}, []float64{1, 3, 3}},
+	}
+	for _, tt := range tests {
+		in := append([]float64(nil), tt.in...)
+		m := New(nil, nil, nil, in, time.Minute, time.Minute)
+		if !reflect.DeepEqual(m.thresholds, tt.want) {
+			t.Errorf("New(%v).thresholds = %v, want %v", tt.in, m.thresholds, tt.want)
+		}
+		if !reflect.DeepEqual(in, tt.in) {
+			t.Errorf("New modified the input thresholds: %v", in)
+		}
+	}
+}
+
+func TestCheckQueuesAlerts(t *testing.T) {
+	m := New(nil, nil, nil, []float64{2, 5}, time.Minute, time.Minute)
+	m.positions = map[string][]watchedPosition{
+		"BTC": {
+			{address: "0xA", coin: "BTC", long: true, liqPx: 97},  // 3%
+			{address: "0xB", coin: "BTC", long: true, liqPx: 80},  // 20%
+			{address: "0xC", coin: "BTC", long: true, liqPx: 101}, // 已越过
+		},
+		"ETH": {{address: "0xA", coin: "ETH", long: false, liqPx: 101}},
+	}
+	m.audiences = map[string]audience{"0xa": {copyUsers: []int64{1}}}
+
+	m.check(map[string]string{"BTC": "100", "ETH": "bad"})
+	if len(m.alerts) != 1 {
+		t.Fatalf("queued %d alerts, want 1", len(m.alerts))
+	}
+	a := <-m.alerts
+	if a.p.address != "0xA" || a.threshold != 5 || !reflect.DeepEqual(a.aud.copyUsers, []int64{1}) {
+		t.Errorf("queued alert = %+v", a)
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// CoinPositioning 聪明钱持仓指数时间序列表（coin_positioning）
+// 由 snapshot 按 trader_positions 周期性聚合，每个币种每次计算一行
+type CoinPositioning struct {
+	ID                    uint      `gorm:"primaryKey;comment:主键ID" json:"id"`
+	Coin                  string    `gorm:"type:varchar(20);not null;index:idx_coin_positioning_coin_time,priority:1;comment:币种" json:"coin"`
+	Time                  int64     `gorm:"not null;index:idx_coin_positioning_coin_time,priority:2;index;comment:计算时间（毫秒时间戳）" json:"time"`
+	LongNotional          string    `gorm:"type:numeric;comment:多仓名义价值" json:"long_notional"`
+	ShortNotional         string    `gorm:"type:numeric;comment:空仓名义价值" json:"short_notional"`
+	NetNotional           string    `gorm:"type:numeric;comment:净多名义价值（多-空）" json:"net_notional"`
+	LongHolders           int       `gorm:"default:0;comment:持多仓交易员数" json:"long_holders"`
+	ShortHolders          int       `gorm:"default:0;comment:持空仓交易员数" json:"short_holders"`
+	LongHolderRatio       string    `gorm:"type:numeric;comment:持多仓交易员占比（0~1）" json:"long_holder_ratio"`
+	MarkPx                string    `gorm:"type:numeric;comment:标记价（持仓价值/仓位大小）" json:"mark_px"`
+	AvgLongEntryPx        string    `gorm:"type:numeric;comment:多仓平均入场价（按仓位大小加权）" json:"avg_long_entry_px"`
+	AvgShortEntryPx       string    `gorm:"type:numeric;comment:空仓平均入场价（按仓位大小加权）" json:"avg_short_entry_px"`
+	LongEntryVsMarkPct    string    `gorm:"type:numeric;comment:多仓平均浮盈百分比（标记价相对多仓均价）" json:"long_entry_vs_mark_pct"`
+	ShortEntryVsMarkPct   string    `gorm:"type:numeric;comment:空仓平均浮盈百分比（空仓均价相对标记价）" json:"short_entry_vs_mark_pct"`
+	ScoreWeightedNetRatio string    `gorm:"type:numeric;comment:综合评分加权净多比例（-1~1，正为偏多）" json:"score_weighted_net_ratio"`
+	ProfitableNetNotional string    `gorm:"type:numeric;comment:历史已实现盈利交易员的净多名义价值" json:"profitable_net_notional"`
+	ProfitableHolders     int       `gorm:"default:0;comment:历史已实现盈利的持仓交易员数" json:"profitable_holders"`
+	CreatedAt             time.Time `gorm:"comment:创建时间" json:"created_at"`
+}
+
+func (CoinPositioning) TableName() string {
+	return "coin_positioning"
+}
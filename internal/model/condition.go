@@ -8,6 +8,13 @@ import (
 
 // Condition 通用筛选条件
 // Field: position_size(仓位大小) / leverage(杠杆倍数) / entry_price(入场价) / position_value(持仓价值) / margin_used(已用保证金) / liq_price(清算价)
+// 交易员分币种条件（trader_coin_statistics，窗口由 trader_metric_period 决定，不支持 90d，配置 90d 时这些条件不通过）:
+// coin_trade_count(交易笔数) / coin_win_rate(胜率) / coin_realized_pnl(已实现盈亏) / coin_avg_holding_ms(平均持仓毫秒) /
+// coin_long_count(多仓笔数) / coin_short_count(空仓笔数) / coin_funding(资金费净额)
+// 交易员综合评分条件（trader_scores，窗口同上）: score(0~100) / score_rank(排名) / score_percentile(百分位 0~1)
+// 交易员指标百分位条件（trader_statistics，窗口同上，取值 0~1）: pct_<指标>(同窗口全体交易员中的百分位) /
+// bucket_pct_<指标>(同账户规模分组内的百分位)，指标为 PercentileMetrics 中的 trader_statistics 列名，如 pct_sharpe、bucket_pct_win_rate；
+// 百分位越高越优于同组交易员，回撤、手续费类指标（LowerIsBetterMetrics）即数值越小百分位越高
 // Operator: < / = / > / >= / <=
 type Condition struct {
 	Field    string `json:"field"`
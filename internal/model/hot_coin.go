@@ -4,12 +4,41 @@ import "time"
 
 // HotCoin 热门币种表（hot_coin）
 type HotCoin struct {
-	ID        int64     `gorm:"primaryKey;comment:主键ID" json:"id"`
-	Coin      string    `gorm:"type:varchar(32);not null;uniqueIndex;comment:币种名称" json:"coin"`
-	CreatedAt time.Time `gorm:"not null;default:now();comment:创建时间" json:"created_at"`
-	UpdatedAt time.Time `gorm:"not null;default:now();comment:更新时间" json:"updated_at"`
+	ID             int64     `gorm:"primaryKey;comment:主键ID" json:"id"`
+	Coin           string    `gorm:"type:varchar(32);not null;uniqueIndex;comment:币种名称" json:"coin"`
+	Rank           int       `gorm:"not null;default:0;index;comment:排名（按持仓交易员数，其次按总名义价值）" json:"rank"`
+	HolderCount    int       `gorm:"not null;default:0;comment:持仓交易员数" json:"holder_count"`
+	LongHolders    int       `gorm:"not null;default:0;comment:持多仓交易员数" json:"long_holders"`
+	ShortHolders   int       `gorm:"not null;default:0;comment:持空仓交易员数" json:"short_holders"`
+	TotalNotional  string    `gorm:"type:numeric;not null;default:0;comment:总持仓名义价值" json:"total_notional"`
+	LongNotional   string    `gorm:"type:numeric;not null;default:0;comment:多仓名义价值" json:"long_notional"`
+	ShortNotional  string    `gorm:"type:numeric;not null;default:0;comment:空仓名义价值" json:"short_notional"`
+	HolderChange   int       `gorm:"not null;default:0;comment:持仓交易员数较上次计算的变化" json:"holder_change"`
+	NotionalChange string    `gorm:"type:numeric;not null;default:0;comment:总名义价值较上次计算的变化" json:"notional_change"`
+	RankChange     int       `gorm:"not null;default:0;comment:排名较上次计算的变化（正数为上升，新上榜为 0）" json:"rank_change"`
+	CreatedAt      time.Time `gorm:"not null;default:now();comment:创建时间" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"not null;default:now();comment:更新时间" json:"updated_at"`
 }
 
 func (HotCoin) TableName() string {
 	return "hot_coin"
 }
+
+// HotCoinHistory 热门币种历史表（hot_coin_history），每次计算追加一批，用于趋势图
+type HotCoinHistory struct {
+	ID            int64     `gorm:"primaryKey;comment:主键ID" json:"id"`
+	Coin          string    `gorm:"type:varchar(32);not null;index:idx_hot_coin_hist_coin_time,priority:1;comment:币种名称" json:"coin"`
+	Time          int64     `gorm:"not null;index:idx_hot_coin_hist_coin_time,priority:2;index;comment:计算时间（毫秒时间戳）" json:"time"`
+	Rank          int       `gorm:"not null;default:0;comment:排名" json:"rank"`
+	HolderCount   int       `gorm:"not null;default:0;comment:持仓交易员数" json:"holder_count"`
+	LongHolders   int       `gorm:"not null;default:0;comment:持多仓交易员数" json:"long_holders"`
+	ShortHolders  int       `gorm:"not null;default:0;comment:持空仓交易员数" json:"short_holders"`
+	TotalNotional string    `gorm:"type:numeric;not null;default:0;comment:总持仓名义价值" json:"total_notional"`
+	LongNotional  string    `gorm:"type:numeric;not null;default:0;comment:多仓名义价值" json:"long_notional"`
+	ShortNotional string    `gorm:"type:numeric;not null;default:0;comment:空仓名义价值" json:"short_notional"`
+	CreatedAt     time.Time `gorm:"not null;default:now();comment:创建时间" json:"created_at"`
+}
+
+func (HotCoinHistory) TableName() string {
+	return "hot_coin_history"
+}
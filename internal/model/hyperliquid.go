@@ -82,6 +82,15 @@ type Fill struct {
 	Tid           int64  `json:"tid"`
 	Cloid         string `json:"cloid"`
 	FeeToken      string `json:"feeToken"`
+
+	Liquidation *FillLiquidation `json:"liquidation,omitempty"`
+}
+
+// FillLiquidation 强平成交附带的强平信息（非强平成交时为空）
+type FillLiquidation struct {
+	LiquidatedUser string `json:"liquidatedUser"`
+	MarkPx         string `json:"markPx"`
+	Method         string `json:"method"`
 }
 
 // --- UserFundingHistory ---
@@ -206,3 +215,41 @@ type SpotBalance struct {
 	Hold     string `json:"hold"`
 	EntryNtl string `json:"entryNtl"`
 }
+
+// --- MetaAndAssetCtxs (永续合约元数据 + 行情上下文) ---
+
+type PerpMeta struct {
+	Universe []PerpAsset `json:"universe"`
+}
+
+type PerpAsset struct {
+	Name        string `json:"name"`
+	SzDecimals  int    `json:"szDecimals"`
+	MaxLeverage int    `json:"maxLeverage"`
+	IsDelisted  bool   `json:"isDelisted"`
+}
+
+// AssetCtx 与 PerpMeta.Universe 按下标一一对应
+type AssetCtx struct {
+	DayNtlVlm    string  `json:"dayNtlVlm"`    // 24h 成交额（USD）
+	OpenInterest string  `json:"openInterest"` // 未平仓量（币本位）
+	MarkPx       string  `json:"markPx"`
+	MidPx        *string `json:"midPx"`
+	OraclePx     string  `json:"oraclePx"`
+	Funding      string  `json:"funding"`
+	PrevDayPx    string  `json:"prevDayPx"`
+}
+
+// --- L2Book (盘口) ---
+
+type L2Book struct {
+	Coin   string       `json:"coin"`
+	Time   int64        `json:"time"`
+	Levels [2][]L2Level `json:"levels"` // [0] 买盘（价格降序），[1] 卖盘（价格升序）
+}
+
+type L2Level struct {
+	Px string `json:"px"`
+	Sz string `json:"sz"`
+	N  int    `json:"n"`
+}
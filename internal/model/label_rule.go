@@ -0,0 +1,80 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LabelRuleSet 状态
+const (
+	LabelRuleSetStatusDraft    = "draft"
+	LabelRuleSetStatusActive   = "active"
+	LabelRuleSetStatusArchived = "archived"
+)
+
+// LabelRuleCondition 标签规则条件：Metric Operator Threshold
+// Operator: < / <= / > / >= / = / !=
+type LabelRuleCondition struct {
+	Metric    string  `json:"metric"`
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+}
+
+// LabelRule 单条标签规则
+//
+// 同一 Category 内按 Priority 从小到大依次匹配，命中第一条即输出其 Label；
+// Multi=true 的类别（如交易风格）输出所有命中的 Label。
+// 所有 Conditions 同时满足且交易笔数 ≥ MinTradeCount 才算命中，无条件的规则作为兜底。
+type LabelRule struct {
+	Category      string               `json:"category"`
+	Label         string               `json:"label"`
+	Priority      int                  `json:"priority"`
+	Multi         bool                 `json:"multi"`
+	MinTradeCount int                  `json:"min_trade_count"`
+	Conditions    []LabelRuleCondition `json:"conditions"`
+}
+
+// LabelRules 规则列表，支持 GORM jsonb 读写
+type LabelRules []LabelRule
+
+func (r LabelRules) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(r)
+	return string(b), err
+}
+
+func (r *LabelRules) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	case nil:
+		*r = nil
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", src)
+	}
+}
+
+// LabelRuleSet 交易员标签规则版本表（label_rule_set）
+//
+// 每个版本保存一整套规则；同一时间只有一个 active 版本，由 snapshot 每轮热加载。
+// Status 状态：draft=草稿 active=生效中 archived=已归档
+type LabelRuleSet struct {
+	ID        uint       `gorm:"primaryKey;comment:主键ID"`
+	Version   int        `gorm:"not null;uniqueIndex;comment:规则版本号"`
+	Status    string     `gorm:"type:varchar(20);not null;default:'draft';index;comment:状态 draft/active/archived"`
+	Rules     LabelRules `gorm:"type:jsonb;not null;default:'[]';comment:规则列表(JSON数组)"`
+	Remark    string     `gorm:"type:varchar(255);not null;default:'';comment:变更说明"`
+	CreatedAt time.Time  `gorm:"comment:创建时间"`
+	UpdatedAt time.Time  `gorm:"comment:更新时间"`
+}
+
+func (LabelRuleSet) TableName() string {
+	return "label_rule_set"
+}
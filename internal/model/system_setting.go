@@ -1,17 +1,51 @@
 package model
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ScoreWeights 交易员综合评分各分项权重（key 见 snapshot.scoreComponents），缺省的分项使用默认权重
+type ScoreWeights map[string]float64
+
+func (w ScoreWeights) Value() (driver.Value, error) {
+	if w == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(w)
+	return string(b), err
+}
+
+func (w *ScoreWeights) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, w)
+	case string:
+		return json.Unmarshal([]byte(v), w)
+	case nil:
+		*w = nil
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", src)
+	}
+}
 
 // SystemSetting 系统设置表（system_setting）
 type SystemSetting struct {
-	ID                     uint      `gorm:"primaryKey;comment:主键ID"`
-	MarketMinutes          int       `gorm:"not null;default:5;comment:行情监控时间窗口（分钟）"`
-	MarketNewPositionCount int       `gorm:"not null;default:3;comment:时间窗口内新仓位数量阈值"`
-	LimitTradingWallet     int       `gorm:"not null;default:0;comment:交易钱包数量限制（0=不限制）"`
-	LimitCopyTrading       int       `gorm:"not null;default:0;comment:跟单交易数量限制（0=不限制）"`
-	LimitWatchedAddress    int       `gorm:"not null;default:0;comment:监控地址数量限制（0=不限制）"`
-	CreatedAt              time.Time `gorm:"comment:创建时间"`
-	UpdatedAt              time.Time `gorm:"comment:更新时间"`
+	ID                     uint         `gorm:"primaryKey;comment:主键ID"`
+	MarketMinutes          int          `gorm:"not null;default:5;comment:行情监控时间窗口（分钟）"`
+	MarketNewPositionCount int          `gorm:"not null;default:3;comment:时间窗口内新仓位数量阈值"`
+	LimitTradingWallet     int          `gorm:"not null;default:0;comment:交易钱包数量限制（0=不限制）"`
+	LimitCopyTrading       int          `gorm:"not null;default:0;comment:跟单交易数量限制（0=不限制）"`
+	LimitWatchedAddress    int          `gorm:"not null;default:0;comment:监控地址数量限制（0=不限制）"`
+	ScoreWeights           ScoreWeights `gorm:"type:jsonb;default:'{}';comment:交易员综合评分权重(JSON对象，缺省项用默认值)"`
+	MarketMinValue         string       `gorm:"type:numeric;default:0;comment:行情预警-时间窗口内同币种同方向新仓位总价值阈值（USD，0=不限制）"`
+	MarketMinScore         string       `gorm:"type:numeric;default:0;comment:行情预警-只统计综合评分(allTime)不低于该值的交易员（0=不限制）"`
+	MarketScoreWeighted    bool         `gorm:"not null;default:false;comment:行情预警-按交易员综合评分加权计数（每人计 评分/100）"`
+	CreatedAt              time.Time    `gorm:"comment:创建时间"`
+	UpdatedAt              time.Time    `gorm:"comment:更新时间"`
 }
 
 func (SystemSetting) TableName() string {
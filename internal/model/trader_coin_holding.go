@@ -9,8 +9,11 @@ import (
 
 // CoinPosition 持仓币种明细
 type CoinPosition struct {
-	Coin string `json:"coin"`
-	Szi  string `json:"szi"`
+	Coin         string `json:"coin"`
+	Szi          string `json:"szi"`
+	Leverage     int    `json:"leverage,omitempty"`     // 杠杆倍数，旧数据无此字段时为 0
+	LeverageType string `json:"leverageType,omitempty"` // 杠杆类型（cross/isolated）
+	EntryPx      string `json:"entryPx,omitempty"`
 }
 
 // CoinPositions 持仓币种列表，支持 GORM jsonb 读写
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// TraderCoinStatistic 交易员分币种统计表（trader_coin_statistics）
+type TraderCoinStatistic struct {
+	ID           uint      `gorm:"primaryKey;comment:主键ID"`
+	Address      string    `gorm:"type:varchar(42);not null;uniqueIndex:idx_coin_stat_addr_coin_window;comment:钱包地址"`
+	Coin         string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_coin_stat_addr_coin_window;index;comment:币种"`
+	Window       string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_coin_stat_addr_coin_window;comment:统计窗口（day/week/month/allTime）"`
+	TradeCount   string    `gorm:"type:numeric;comment:已完成交易笔数"`
+	ProfitCount  string    `gorm:"type:numeric;comment:盈利笔数"`
+	WinRate      string    `gorm:"type:numeric;comment:胜率"`
+	RealizedPnl  string    `gorm:"type:numeric;comment:已实现盈亏"`
+	AvgHoldingMs string    `gorm:"type:numeric;comment:平均持仓时间（毫秒）"`
+	LongCount    string    `gorm:"type:numeric;comment:多仓笔数"`
+	LongPnl      string    `gorm:"type:numeric;comment:多仓已实现盈亏"`
+	ShortCount   string    `gorm:"type:numeric;comment:空仓笔数"`
+	ShortPnl     string    `gorm:"type:numeric;comment:空仓已实现盈亏"`
+	Funding      string    `gorm:"type:numeric;comment:资金费净额（正=收入，负=支出）"`
+	CreatedAt    time.Time `gorm:"comment:创建时间"`
+	UpdatedAt    time.Time `gorm:"comment:更新时间"`
+}
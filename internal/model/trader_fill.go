@@ -21,5 +21,6 @@ type TraderFill struct {
 	Tid           int64     `gorm:"not null;uniqueIndex:uidx_fill;comment:成交ID"`
 	Cloid         string    `gorm:"type:varchar(66);comment:客户端订单ID"`
 	FeeToken      string    `gorm:"type:varchar(10);comment:手续费计价币种"`
+	Liquidation   bool      `gorm:"not null;default:false;comment:是否为被强平成交"`
 	CreatedAt     time.Time `gorm:"comment:创建时间"`
 }
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// TraderLabelHistory 动作
+const (
+	LabelActionAdded   = "added"
+	LabelActionRemoved = "removed"
+)
+
+// TraderLabelHistory 交易员标签变更历史表（trader_label_history）
+type TraderLabelHistory struct {
+	ID          uint           `gorm:"primaryKey;comment:主键ID"`
+	Address     string         `gorm:"type:varchar(42);not null;index:idx_label_hist_addr_time;comment:钱包地址"`
+	Label       string         `gorm:"type:varchar(32);not null;index;comment:标签"`
+	Action      string         `gorm:"type:varchar(10);not null;comment:变更动作 added/removed"`
+	RuleVersion int            `gorm:"not null;default:0;comment:计算时使用的标签规则版本"`
+	Metrics     datatypes.JSON `gorm:"type:jsonb;comment:变更时的标签指标快照"`
+	CreatedAt   time.Time      `gorm:"index:idx_label_hist_addr_time;comment:创建时间"`
+}
+
+func (TraderLabelHistory) TableName() string {
+	return "trader_label_history"
+}
@@ -20,6 +20,9 @@ type TraderPosition struct {
 	CumFundingAllTime     string `gorm:"type:numeric;comment:累计资金费（全部时间）"`
 	CumFundingSinceOpen   string `gorm:"type:numeric;comment:累计资金费（开仓以来）"`
 	CumFundingSinceChange string `gorm:"type:numeric;comment:累计资金费（最近变更以来）"`
+	MarkPx                string `gorm:"type:numeric;comment:标记价（持仓价值/|仓位大小|）"`
+	LiqDistancePct        *float64 `gorm:"type:numeric;comment:距清算价百分比（按标记价计算需不利波动的幅度，无清算价时为空）"`
+	Concentration         string `gorm:"type:numeric;comment:仓位集中度（该仓位名义价值/全部持仓名义价值）"`
 	CreatedAt             time.Time `gorm:"comment:创建时间"`
 	UpdatedAt             time.Time `gorm:"comment:更新时间"`
 }
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// TraderPositionEvent 事件类型
+const (
+	PositionEventOpened            = "opened"
+	PositionEventIncreased         = "increased"
+	PositionEventDecreased         = "decreased"
+	PositionEventClosed            = "closed"
+	PositionEventFlipped           = "flipped"
+	PositionEventLeverageChanged   = "leverage_changed"
+	PositionEventMarginModeChanged = "margin_mode_changed"
+)
+
+// TraderPositionEvent 交易员持仓变更事件表（trader_position_events）
+// 由 snapshot 对比相邻两次持仓快照得到，Before* 为变更前、After* 为变更后（开仓时 Before* 为空，平仓时 After* 为空）
+type TraderPositionEvent struct {
+	ID                  uint      `gorm:"primaryKey;comment:主键ID"`
+	Address             string    `gorm:"type:varchar(42);not null;index:idx_pos_event_addr_coin_time,priority:1;comment:钱包地址"`
+	Coin                string    `gorm:"type:varchar(20);not null;index:idx_pos_event_addr_coin_time,priority:2;comment:币种"`
+	Time                int64     `gorm:"not null;index:idx_pos_event_addr_coin_time,priority:3;index;comment:观测到变更的时间（毫秒时间戳）"`
+	EventType           string    `gorm:"type:varchar(30);not null;index;comment:事件类型 opened/increased/decreased/closed/flipped/leverage_changed/margin_mode_changed"`
+	BeforeSzi           string    `gorm:"type:numeric;comment:变更前仓位大小（正多负空）"`
+	AfterSzi            string    `gorm:"type:numeric;comment:变更后仓位大小（正多负空）"`
+	BeforeEntryPx       string    `gorm:"type:numeric;comment:变更前入场价"`
+	AfterEntryPx        string    `gorm:"type:numeric;comment:变更后入场价"`
+	BeforePositionValue string    `gorm:"type:numeric;comment:变更前持仓价值"`
+	AfterPositionValue  string    `gorm:"type:numeric;comment:变更后持仓价值"`
+	BeforeLeverage      int       `gorm:"default:0;comment:变更前杠杆倍数"`
+	AfterLeverage       int       `gorm:"default:0;comment:变更后杠杆倍数"`
+	BeforeLeverageType  string    `gorm:"type:varchar(20);default:'';comment:变更前杠杆类型（cross/isolated）"`
+	AfterLeverageType   string    `gorm:"type:varchar(20);default:'';comment:变更后杠杆类型（cross/isolated）"`
+	BeforeUnrealizedPnl string    `gorm:"type:numeric;comment:变更前未实现盈亏"`
+	AfterUnrealizedPnl  string    `gorm:"type:numeric;comment:变更后未实现盈亏"`
+	CreatedAt           time.Time `gorm:"comment:创建时间"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// TraderScore 交易员综合跟单价值评分表（trader_scores）
+//
+// 各分项为该窗口内全体交易员中的百分位（0~1，越高越好），Score 为加权后的 0~100 分
+type TraderScore struct {
+	ID               uint      `gorm:"primaryKey;comment:主键ID"`
+	Address          string    `gorm:"type:varchar(42);not null;uniqueIndex:idx_score_addr_window;comment:钱包地址"`
+	Window           string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_score_addr_window;index:idx_score_window_rank;comment:统计窗口（day/week/month/allTime）"`
+	Score            string    `gorm:"type:numeric;comment:综合评分（0~100）"`
+	Rank             int       `gorm:"not null;default:0;index:idx_score_window_rank;comment:窗口内排名（1为最佳）"`
+	Percentile       string    `gorm:"type:numeric;comment:窗口内百分位（0~1）"`
+	RiskAdjusted     string    `gorm:"type:numeric;comment:风险调整收益分项（索提诺）"`
+	DrawdownScore    string    `gorm:"type:numeric;comment:回撤分项（回撤越小越高）"`
+	Consistency      string    `gorm:"type:numeric;comment:稳定性分项（盈亏比）"`
+	SampleSize       string    `gorm:"type:numeric;comment:样本量分项（交易笔数）"`
+	Recency          string    `gorm:"type:numeric;comment:活跃度分项（距最近一笔交易越近越高）"`
+	FeeDrag          string    `gorm:"type:numeric;comment:手续费拖累分项（手续费占毛盈亏越低越高）"`
+	LiquidationScore string    `gorm:"type:numeric;comment:强平记录分项（强平次数越少越高）"`
+	CreatedAt        time.Time `gorm:"comment:创建时间"`
+	UpdatedAt        time.Time `gorm:"comment:更新时间"`
+}
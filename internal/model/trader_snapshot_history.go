@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// TraderSnapshotHistory 交易员快照历史表（trader_snapshot_history）
+// 每轮快照追加一行，按保留策略降采样：2天内全部保留、90天内每小时一条、更早每天一条
+type TraderSnapshotHistory struct {
+	ID                 uint      `gorm:"primaryKey;comment:主键ID"`
+	Address            string    `gorm:"type:varchar(42);not null;index:idx_snap_hist_addr_time,priority:1;comment:钱包地址"`
+	Time               int64     `gorm:"not null;index:idx_snap_hist_addr_time,priority:2;index;comment:快照时间（毫秒时间戳）"`
+	TotalValue         string    `gorm:"type:numeric;comment:总价值（永续账户价值+现货）"`
+	PerpValue          string    `gorm:"type:numeric;comment:永续合约价值"`
+	SpotValue          string    `gorm:"type:numeric;comment:现货价值"`
+	EffLeverage        string    `gorm:"type:numeric;comment:有效杠杆（总持仓名义价值/账户价值）"`
+	MarginUsageRate    string    `gorm:"type:numeric;comment:保证金使用率"`
+	TotalMarginUsed    string    `gorm:"type:numeric;comment:已用保证金"`
+	PositionValue      string    `gorm:"type:numeric;comment:总持仓价值"`
+	LongPositionValue  string    `gorm:"type:numeric;comment:多头持仓价值"`
+	ShortPositionValue string    `gorm:"type:numeric;comment:空头持仓价值"`
+	PositionCount      int       `gorm:"default:0;comment:总持仓数"`
+	LongPositionCount  int       `gorm:"default:0;comment:多头持仓数"`
+	ShortPositionCount int       `gorm:"default:0;comment:空头持仓数"`
+	UnrealizedPnl      string    `gorm:"type:numeric;comment:未实现盈亏"`
+	WeightedLeverage   string    `gorm:"type:numeric;comment:名义价值加权杠杆"`
+	MaxConcentration   string    `gorm:"type:numeric;comment:最大单仓集中度（0~1）"`
+	MinLiqDistancePct  *float64  `gorm:"type:numeric;comment:距清算最近持仓的距离百分比"`
+	CrossMarginBuffer  string    `gorm:"type:numeric;comment:全仓保证金缓冲"`
+	CreatedAt          time.Time `gorm:"comment:创建时间"`
+}
+
+func (TraderSnapshotHistory) TableName() string {
+	return "trader_snapshot_history"
+}
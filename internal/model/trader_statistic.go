@@ -4,36 +4,75 @@ import (
 	"time"
 
 	"github.com/lib/pq"
+	"gorm.io/datatypes"
 )
 
 // TraderStatistic 交易员统计指标表（trader_statistics）
 type TraderStatistic struct {
-	ID                 uint      `gorm:"primaryKey;comment:主键ID"`
-	Address            string    `gorm:"type:varchar(42);not null;uniqueIndex:idx_stat_addr_window;comment:钱包地址"`
-	Window             string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_stat_addr_window;comment:统计窗口（day/week/month/allTime）"`
-	Sharpe             string    `gorm:"type:numeric;comment:夏普比率"`
-	Drawdown           string    `gorm:"type:numeric;comment:最大回撤"`
-	PositionCount      string    `gorm:"type:numeric;comment:持仓数"`
-	TotalValue         string    `gorm:"type:numeric;comment:账户总价值"`
-	PerpValue          string    `gorm:"type:numeric;comment:永续合约总价值"`
-	PositionValue      string    `gorm:"type:numeric;comment:持仓价值"`
-	LongPositionValue  string    `gorm:"type:numeric;comment:多仓仓位价值"`
-	ShortPositionValue string    `gorm:"type:numeric;comment:空仓仓位价值"`
-	MarginUsage        string    `gorm:"type:numeric;comment:保证金使用率"`
-	UsedMargin         string    `gorm:"type:numeric;comment:已用保证金"`
-	ProfitCount        string    `gorm:"type:numeric;comment:盈利次数"`
-	WinRate            string    `gorm:"type:numeric;comment:胜率"`
-	TotalPnl           string    `gorm:"type:numeric;comment:总盈亏"`
-	LongCount          string    `gorm:"type:numeric;comment:多仓数"`
-	LongRealizedPnl    string    `gorm:"type:numeric;comment:多仓已实现盈亏"`
-	LongWinRate        string    `gorm:"type:numeric;comment:多仓胜率"`
-	ShortCount         string    `gorm:"type:numeric;comment:空仓数"`
-	ShortRealizedPnl   string    `gorm:"type:numeric;comment:空仓已实现盈亏"`
-	ShortWinRate       string    `gorm:"type:numeric;comment:空仓胜率"`
-	UnrealizedPnl        string    `gorm:"type:numeric;comment:未实现盈亏"`
-	AvgLeverage          string    `gorm:"type:numeric;comment:平均杠杆"`
-	TotalRealizedPnl     string    `gorm:"type:numeric;comment:已实现总盈亏（正为盈利，负为亏损）"`
-	Coins                pq.StringArray `gorm:"type:text[];default:'{}';comment:交易过的币种"`
-	CreatedAt            time.Time      `gorm:"comment:创建时间"`
-	UpdatedAt            time.Time      `gorm:"comment:更新时间"`
+	ID                 uint           `gorm:"primaryKey;comment:主键ID"`
+	Address            string         `gorm:"type:varchar(42);not null;uniqueIndex:idx_stat_addr_window;comment:钱包地址"`
+	Window             string         `gorm:"type:varchar(20);not null;uniqueIndex:idx_stat_addr_window;comment:统计窗口（day/week/month/allTime）"`
+	Sharpe             string         `gorm:"type:numeric;comment:夏普比率"`
+	Drawdown           string         `gorm:"type:numeric;comment:最大回撤"`
+	PositionCount      string         `gorm:"type:numeric;comment:持仓数"`
+	TotalValue         string         `gorm:"type:numeric;comment:账户总价值"`
+	PerpValue          string         `gorm:"type:numeric;comment:永续合约总价值"`
+	PositionValue      string         `gorm:"type:numeric;comment:持仓价值"`
+	LongPositionValue  string         `gorm:"type:numeric;comment:多仓仓位价值"`
+	ShortPositionValue string         `gorm:"type:numeric;comment:空仓仓位价值"`
+	MarginUsage        string         `gorm:"type:numeric;comment:保证金使用率"`
+	UsedMargin         string         `gorm:"type:numeric;comment:已用保证金"`
+	ProfitCount        string         `gorm:"type:numeric;comment:盈利次数"`
+	WinRate            string         `gorm:"type:numeric;comment:胜率"`
+	TotalPnl           string         `gorm:"type:numeric;comment:总盈亏"`
+	LongCount          string         `gorm:"type:numeric;comment:多仓数"`
+	LongRealizedPnl    string         `gorm:"type:numeric;comment:多仓已实现盈亏"`
+	LongWinRate        string         `gorm:"type:numeric;comment:多仓胜率"`
+	ShortCount         string         `gorm:"type:numeric;comment:空仓数"`
+	ShortRealizedPnl   string         `gorm:"type:numeric;comment:空仓已实现盈亏"`
+	ShortWinRate       string         `gorm:"type:numeric;comment:空仓胜率"`
+	UnrealizedPnl      string         `gorm:"type:numeric;comment:未实现盈亏"`
+	AvgLeverage        string         `gorm:"type:numeric;comment:平均杠杆"`
+	TotalRealizedPnl   string         `gorm:"type:numeric;comment:已实现总盈亏（正为盈利，负为亏损）"`
+	Volume             string         `gorm:"type:numeric;comment:成交额（USDC 计价成交）"`
+	TotalFee           string         `gorm:"type:numeric;comment:已付手续费（USDC）"`
+	MakerRebate        string         `gorm:"type:numeric;comment:Maker 返佣（USDC）"`
+	NetFee             string         `gorm:"type:numeric;comment:净手续费（已付手续费 - Maker 返佣）"`
+	FeeRate            string         `gorm:"type:numeric;comment:有效费率（净手续费 / 成交额）"`
+	FeePnlRatio        string         `gorm:"type:numeric;comment:手续费占毛盈亏比例（净手续费 / 毛盈亏，毛盈亏≤0 时为 0）"`
+	ProfitFactor       string         `gorm:"type:numeric;comment:盈亏比（总盈利/总亏损，无亏损时为 999）"`
+	Expectancy         string         `gorm:"type:numeric;comment:期望值（单笔平均盈亏）"`
+	RiskRewardRatio    string         `gorm:"type:numeric;comment:风险收益比（平均盈利/平均亏损，无亏损时为 999）"`
+	AvgHoldingMs       string         `gorm:"type:numeric;comment:平均持仓时间（毫秒）"`
+	AvgProfitPerWin    string         `gorm:"type:numeric;comment:平均单笔盈利"`
+	ActiveDays90       string         `gorm:"type:numeric;comment:近90天活跃天数"`
+	Rolling30dReturn   string         `gorm:"type:numeric;comment:近30天滚动收益率"`
+	Drawdown90         string         `gorm:"type:numeric;comment:近90天最大回撤"`
+	Sortino            string         `gorm:"type:numeric;comment:索提诺比率"`
+	Calmar             string         `gorm:"type:numeric;comment:卡玛比率（年化收益/最大回撤）"`
+	Coins              pq.StringArray `gorm:"type:text[];default:'{}';comment:交易过的币种"`
+	SizeBucket         string         `gorm:"type:varchar(20);not null;default:'';comment:账户规模分组（account_size 标签 small/medium/whale）"`
+	Percentiles        datatypes.JSON `gorm:"type:jsonb;comment:各指标在同窗口全体交易员中的百分位（0~1，值越大百分位越高）"`
+	BucketPercentiles  datatypes.JSON `gorm:"type:jsonb;comment:各指标在同窗口、同账户规模分组内的百分位（0~1）"`
+	CreatedAt          time.Time      `gorm:"comment:创建时间"`
+	UpdatedAt          time.Time      `gorm:"comment:更新时间"`
+}
+
+// PercentileMetrics 计算百分位（Percentiles / BucketPercentiles）的 trader_statistics 数值列。
+// 百分位越高表示数值越大；LowerIsBetterMetrics 中的指标反向排名，百分位越高表示数值越小（表现越好）
+var PercentileMetrics = []string{
+	"sharpe", "sortino", "calmar", "drawdown", "drawdown90",
+	"win_rate", "long_win_rate", "short_win_rate", "profit_count", "long_count", "short_count",
+	"total_pnl", "total_realized_pnl", "long_realized_pnl", "short_realized_pnl", "unrealized_pnl",
+	"profit_factor", "expectancy", "risk_reward_ratio", "avg_profit_per_win", "avg_holding_ms",
+	"active_days90", "rolling30d_return",
+	"total_value", "perp_value", "position_value", "long_position_value", "short_position_value",
+	"position_count", "margin_usage", "used_margin", "avg_leverage",
+	"volume", "total_fee", "maker_rebate", "net_fee", "fee_rate", "fee_pnl_ratio",
+}
+
+// LowerIsBetterMetrics 数值越小越好的百分位指标（回撤、手续费负担），按降序计算百分位
+var LowerIsBetterMetrics = map[string]struct{}{
+	"drawdown": {}, "drawdown90": {},
+	"total_fee": {}, "net_fee": {}, "fee_rate": {}, "fee_pnl_ratio": {},
 }
@@ -0,0 +1,198 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/utility"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// tradeAgg 单个窗口内 completed_trades 的聚合结果，由数据库直接计算
+type tradeAgg struct {
+	tradeCount       int
+	profitCount      int
+	lossCount        int
+	totalProfit      float64 // Σ pnl (pnl > 0)
+	totalLoss        float64 // Σ -pnl (pnl < 0)
+	totalPnl         float64
+	holdingMs        float64 // Σ (end_time - start_time)
+	longCount        int
+	longProfitCount  int
+	longPnl          float64
+	shortCount       int
+	shortProfitCount int
+	shortPnl         float64
+	tradeCount90     int // 近90天平仓笔数
+	tradeCount30     int // 近30天平仓笔数
+	activeDays90     int // 近90天有平仓的自然日数
+	coins            []string
+}
+
+func (a tradeAgg) stats() tradeStats {
+	ts := tradeStats{
+		profitCount:      a.profitCount,
+		totalPnl:         a.totalPnl,
+		longCount:        a.longCount,
+		longProfitCount:  a.longProfitCount,
+		longPnl:          a.longPnl,
+		shortCount:       a.shortCount,
+		shortProfitCount: a.shortProfitCount,
+		shortPnl:         a.shortPnl,
+		totalRealizedPnl: a.totalPnl,
+		coins:            a.coins,
+	}
+	if a.tradeCount > 0 {
+		ts.winRate = float64(a.profitCount) / float64(a.tradeCount)
+	}
+	if a.longCount > 0 {
+		ts.longWinRate = float64(a.longProfitCount) / float64(a.longCount)
+	}
+	if a.shortCount > 0 {
+		ts.shortWinRate = float64(a.shortProfitCount) / float64(a.shortCount)
+	}
+	if ts.coins == nil {
+		ts.coins = []string{}
+	}
+	return ts
+}
+
+// loadTradeAggs 在数据库内按窗口聚合交易员的 completed_trades，返回 window -> 聚合结果；
+// 没有交易的窗口不在结果中，取零值即可
+func loadTradeAggs(db *gorm.DB, address string) (map[string]tradeAgg, error) {
+	var rows []struct {
+		Win              string
+		TradeCount       int
+		ProfitCount      int
+		LossCount        int
+		TotalProfit      float64
+		TotalLoss        float64
+		TotalPnl         float64
+		HoldingMs        float64
+		LongCount        int
+		LongProfitCount  int
+		LongPnl          float64
+		ShortCount       int
+		ShortProfitCount int
+		ShortPnl         float64
+		TradeCount90     int
+		TradeCount30     int
+		ActiveDays90     int
+		Coins            pq.StringArray `gorm:"type:text[]"`
+	}
+
+	now := time.Now()
+	cutoff90 := now.Add(-90 * 24 * time.Hour).UnixMilli()
+	cutoff30 := now.Add(-30 * 24 * time.Hour).UnixMilli()
+
+	err := db.Raw(`
+		SELECT w.win,
+			COUNT(*) AS trade_count,
+			COUNT(*) FILTER (WHERE t.pnl > 0) AS profit_count,
+			COUNT(*) FILTER (WHERE t.pnl < 0) AS loss_count,
+			COALESCE(SUM(t.pnl) FILTER (WHERE t.pnl > 0), 0) AS total_profit,
+			COALESCE(-SUM(t.pnl) FILTER (WHERE t.pnl < 0), 0) AS total_loss,
+			COALESCE(SUM(t.pnl), 0) AS total_pnl,
+			COALESCE(SUM(t.end_time - t.start_time), 0) AS holding_ms,
+			COUNT(*) FILTER (WHERE t.direction = 'long') AS long_count,
+			COUNT(*) FILTER (WHERE t.direction = 'long' AND t.pnl > 0) AS long_profit_count,
+			COALESCE(SUM(t.pnl) FILTER (WHERE t.direction = 'long'), 0) AS long_pnl,
+			COUNT(*) FILTER (WHERE t.direction = 'short') AS short_count,
+			COUNT(*) FILTER (WHERE t.direction = 'short' AND t.pnl > 0) AS short_profit_count,
+			COALESCE(SUM(t.pnl) FILTER (WHERE t.direction = 'short'), 0) AS short_pnl,
+			COUNT(*) FILTER (WHERE t.end_time >= ?) AS trade_count90,
+			COUNT(*) FILTER (WHERE t.end_time >= ?) AS trade_count30,
+			COUNT(DISTINCT to_timestamp(t.end_time / 1000.0)::date) FILTER (WHERE t.end_time >= ?) AS active_days90,
+			array_agg(DISTINCT t.coin ORDER BY t.coin) AS coins
+		FROM (VALUES ('day', ?::bigint), ('week', ?::bigint), ('month', ?::bigint), ('allTime', ?::bigint)) AS w(win, cutoff)
+		JOIN completed_trades t ON t.address = ? AND t.end_time >= w.cutoff
+		GROUP BY w.win`,
+		cutoff90, cutoff30, cutoff90,
+		utility.WindowCutoff("day"), utility.WindowCutoff("week"),
+		utility.WindowCutoff("month"), utility.WindowCutoff("allTime"),
+		address,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("aggregate trades: %w", err)
+	}
+
+	out := make(map[string]tradeAgg, len(rows))
+	for _, r := range rows {
+		out[r.Win] = tradeAgg{
+			tradeCount:       r.TradeCount,
+			profitCount:      r.ProfitCount,
+			lossCount:        r.LossCount,
+			totalProfit:      r.TotalProfit,
+			totalLoss:        r.TotalLoss,
+			totalPnl:         r.TotalPnl,
+			holdingMs:        r.HoldingMs,
+			longCount:        r.LongCount,
+			longProfitCount:  r.LongProfitCount,
+			longPnl:          r.LongPnl,
+			shortCount:       r.ShortCount,
+			shortProfitCount: r.ShortProfitCount,
+			shortPnl:         r.ShortPnl,
+			tradeCount90:     r.TradeCount90,
+			tradeCount30:     r.TradeCount30,
+			activeDays90:     r.ActiveDays90,
+			coins:            r.Coins,
+		}
+	}
+	return out, nil
+}
+
+// loadCoinTradeAggs 按窗口、币种聚合 completed_trades，返回 window -> coin -> 统计
+func loadCoinTradeAggs(db *gorm.DB, address string) (map[string]map[string]*coinTradeStats, error) {
+	var rows []struct {
+		Win         string
+		Coin        string
+		TradeCount  int
+		ProfitCount int
+		RealizedPnl float64
+		HoldingMs   float64
+		LongCount   int
+		LongPnl     float64
+		ShortCount  int
+		ShortPnl    float64
+	}
+
+	err := db.Raw(`
+		SELECT w.win, t.coin,
+			COUNT(*) AS trade_count,
+			COUNT(*) FILTER (WHERE t.pnl > 0) AS profit_count,
+			COALESCE(SUM(t.pnl), 0) AS realized_pnl,
+			COALESCE(SUM(t.end_time - t.start_time), 0) AS holding_ms,
+			COUNT(*) FILTER (WHERE t.direction = 'long') AS long_count,
+			COALESCE(SUM(t.pnl) FILTER (WHERE t.direction = 'long'), 0) AS long_pnl,
+			COUNT(*) FILTER (WHERE t.direction = 'short') AS short_count,
+			COALESCE(SUM(t.pnl) FILTER (WHERE t.direction = 'short'), 0) AS short_pnl
+		FROM (VALUES ('day', ?::bigint), ('week', ?::bigint), ('month', ?::bigint), ('allTime', ?::bigint)) AS w(win, cutoff)
+		JOIN completed_trades t ON t.address = ? AND t.end_time >= w.cutoff
+		GROUP BY w.win, t.coin`,
+		utility.WindowCutoff("day"), utility.WindowCutoff("week"),
+		utility.WindowCutoff("month"), utility.WindowCutoff("allTime"),
+		address,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("aggregate coin trades: %w", err)
+	}
+
+	out := make(map[string]map[string]*coinTradeStats)
+	for _, r := range rows {
+		if out[r.Win] == nil {
+			out[r.Win] = make(map[string]*coinTradeStats)
+		}
+		out[r.Win][r.Coin] = &coinTradeStats{
+			tradeCount:  r.TradeCount,
+			profitCount: r.ProfitCount,
+			realizedPnl: r.RealizedPnl,
+			totalHoldMs: r.HoldingMs,
+			longCount:   r.LongCount,
+			longPnl:     r.LongPnl,
+			shortCount:  r.ShortCount,
+			shortPnl:    r.ShortPnl,
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"gorm.io/gorm"
+)
+
+type coinTradeStats struct {
+	tradeCount   int
+	profitCount  int
+	realizedPnl  float64
+	totalHoldMs  float64
+	longCount    int
+	longPnl      float64
+	shortCount   int
+	shortPnl     float64
+	fundingTotal float64
+}
+
+// updateCoinStatistics 重算交易员所有窗口的分币种统计
+func (s *Syncer) updateCoinStatistics(address string) error {
+	aggs, err := loadCoinTradeAggs(s.db, address)
+	if err != nil {
+		return err
+	}
+	funding, err := s.loadFundingByCoin(address)
+	if err != nil {
+		return fmt.Errorf("load funding: %w", err)
+	}
+
+	var records []model.TraderCoinStatistic
+	for _, window := range allWindows {
+		records = append(records, buildCoinStats(address, window, aggs[window], funding[window])...)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("address = ?", address).Delete(&model.TraderCoinStatistic{}).Error; err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(records, 500).Error
+	})
+}
+
+func buildCoinStats(address, window string, byCoin map[string]*coinTradeStats, funding map[string]float64) []model.TraderCoinStatistic {
+	if byCoin == nil {
+		byCoin = make(map[string]*coinTradeStats)
+	}
+	get := func(coin string) *coinTradeStats {
+		cs, ok := byCoin[coin]
+		if !ok {
+			cs = &coinTradeStats{}
+			byCoin[coin] = cs
+		}
+		return cs
+	}
+
+	// 仍在持仓、尚未形成 completed trade 的币种也会产生资金费
+	for coin, usdc := range funding {
+		get(coin).fundingTotal = usdc
+	}
+
+	coins := make([]string, 0, len(byCoin))
+	for c := range byCoin {
+		coins = append(coins, c)
+	}
+	sort.Strings(coins)
+
+	out := make([]model.TraderCoinStatistic, 0, len(coins))
+	for _, coin := range coins {
+		cs := byCoin[coin]
+		var winRate, avgHoldMs float64
+		if cs.tradeCount > 0 {
+			winRate = float64(cs.profitCount) / float64(cs.tradeCount)
+			avgHoldMs = cs.totalHoldMs / float64(cs.tradeCount)
+		}
+		out = append(out, model.TraderCoinStatistic{
+			Address:      address,
+			Coin:         coin,
+			Window:       window,
+			TradeCount:   strconv.Itoa(cs.tradeCount),
+			ProfitCount:  strconv.Itoa(cs.profitCount),
+			WinRate:      utility.FmtFloat(winRate),
+			RealizedPnl:  utility.FmtFloat(cs.realizedPnl),
+			AvgHoldingMs: utility.FmtFloat(avgHoldMs),
+			LongCount:    strconv.Itoa(cs.longCount),
+			LongPnl:      utility.FmtFloat(cs.longPnl),
+			ShortCount:   strconv.Itoa(cs.shortCount),
+			ShortPnl:     utility.FmtFloat(cs.shortPnl),
+			Funding:      utility.FmtFloat(cs.fundingTotal),
+		})
+	}
+	return out
+}
+
+// loadFundingByCoin 按窗口、币种聚合 trader_fundings，返回 window -> coin -> usdc
+func (s *Syncer) loadFundingByCoin(address string) (map[string]map[string]float64, error) {
+	var rows []struct {
+		Win  string
+		Coin string
+		Usdc float64
+	}
+
+	err := s.db.Raw(`
+		SELECT w.win, f.coin, SUM(f.usdc) AS usdc
+		FROM (VALUES ('day', ?::bigint), ('week', ?::bigint), ('month', ?::bigint), ('allTime', ?::bigint)) AS w(win, cutoff)
+		JOIN trader_fundings f ON f.address = ? AND f.time >= w.cutoff
+		GROUP BY w.win, f.coin`,
+		utility.WindowCutoff("day"), utility.WindowCutoff("week"),
+		utility.WindowCutoff("month"), utility.WindowCutoff("allTime"),
+		address,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]map[string]float64)
+	for _, r := range rows {
+		if out[r.Win] == nil {
+			out[r.Win] = make(map[string]float64)
+		}
+		out[r.Win][r.Coin] = r.Usdc
+	}
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/consts"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"gorm.io/gorm"
+)
+
+// statsMaxAge 输入未变化时统计的最长有效期；窗口随时间滑动，超过该时长仍需全量重算
+const statsMaxAge = time.Hour
+
+// statsDirty 判断交易员的统计是否需要全量重算：
+// 从未计算、没有标签指标快照、超过 statsMaxAge、标签规则在上次计算后发生变更，
+// 或 completed_trades / trader_account_values / trader_fills / trader_fundings 有晚于水位线的写入
+func (s *Syncer) statsDirty(trader *model.Trader, now time.Time) (bool, error) {
+	last := trader.StatsComputedAt
+	if last == nil || len(trader.LabelMetrics) == 0 || now.Sub(*last) >= statsMaxAge {
+		return true, nil
+	}
+	if set := s.labelRules.Load(); set != nil && last.Before(set.UpdatedAt) {
+		return true, nil
+	}
+
+	var row struct {
+		ChangedAt *time.Time
+	}
+	err := s.db.Raw(`
+		SELECT GREATEST(
+			(SELECT MAX(created_at) FROM completed_trades WHERE address = ?),
+			(SELECT MAX(updated_at) FROM trader_account_values WHERE address = ?),
+			(SELECT MAX(created_at) FROM trader_fills WHERE address = ?),
+			(SELECT MAX(created_at) FROM trader_fundings WHERE address = ?)
+		) AS changed_at`,
+		trader.Address, trader.Address, trader.Address, trader.Address,
+	).Scan(&row).Error
+	if err != nil {
+		return false, err
+	}
+	return row.ChangedAt != nil && row.ChangedAt.After(*last), nil
+}
+
+// refreshSnapStats 输入未变化时只刷新来自持仓快照的统计列，交易/净值类指标保持上次结果；
+// 依赖快照的标签（如 account_size）与 size_bucket 用上次的标签指标加最新快照总价值重新计算
+func (s *Syncer) refreshSnapStats(trader *model.Trader) error {
+	var vals map[string]float64
+	if err := json.Unmarshal(trader.LabelMetrics, &vals); err != nil {
+		return fmt.Errorf("unmarshal label metrics %s: %w", utility.Abbr(trader.Address), err)
+	}
+	m := labelMetricsFromSnapshot(vals)
+	m.totalValue, _ = strconv.ParseFloat(trader.SnapTotalValue, 64)
+	rules := s.currentLabelRules()
+
+	statUpdates := map[string]interface{}{
+		"position_count":       strconv.Itoa(trader.SnapPositionCount),
+		"total_value":          utility.OrZero(trader.SnapTotalValue),
+		"perp_value":           utility.OrZero(trader.SnapPerpValue),
+		"position_value":       utility.OrZero(trader.SnapPositionValue),
+		"long_position_value":  utility.OrZero(trader.SnapLongPositionValue),
+		"short_position_value": utility.OrZero(trader.SnapShortPositionValue),
+		"margin_usage":         utility.OrZero(trader.SnapMarginUsageRate),
+		"used_margin":          utility.OrZero(trader.SnapTotalMarginUsed),
+		"unrealized_pnl":       utility.OrZero(trader.SnapUnrealizedPnl),
+		"avg_leverage":         utility.OrZero(trader.SnapEffLeverage),
+		"size_bucket":          categoryLabel(rules, m, consts.LabelCategoryAccountSize),
+	}
+	err := s.saveLabels(trader, computeLabels(rules, m), m, nil, func(tx *gorm.DB) error {
+		return tx.Model(&model.TraderStatistic{}).Where("address = ?", trader.Address).Updates(statUpdates).Error
+	})
+	if err != nil {
+		return fmt.Errorf("refresh snap stats %s: %w", utility.Abbr(trader.Address), err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/hypercopy/crawler/internal/model"
+	"gorm.io/gorm"
+)
+
+// LabelDiff 单个标签在两套规则下的命中变化
+type LabelDiff struct {
+	Category string
+	Label    string
+	Before   int // 当前规则下命中的交易员数
+	After    int // 候选规则下命中的交易员数
+	Added    int // 新增该标签的交易员数
+	Removed  int // 失去该标签的交易员数
+}
+
+// LabelDryRunReport 候选规则与当前 active 规则的对比结果
+type LabelDryRunReport struct {
+	BaseVersion      int
+	CandidateVersion int
+	Traders          int // 参与评估的交易员数
+	Changed          int // 标签发生变化的交易员数
+	Diffs            []*LabelDiff
+}
+
+// DryRunLabelRules 用候选版本重新计算所有交易员的标签，统计与 active 版本相比的变化，不写库
+func DryRunLabelRules(db *gorm.DB, candidateVersion int) (*LabelDryRunReport, error) {
+	base, err := loadActiveLabelRuleSet(db)
+	if err != nil {
+		return nil, fmt.Errorf("load active rules: %w", err)
+	}
+
+	var candidate model.LabelRuleSet
+	if err := db.Where("version = ?", candidateVersion).First(&candidate).Error; err != nil {
+		return nil, fmt.Errorf("load rules version %d: %w", candidateVersion, err)
+	}
+	if err := ValidateLabelRules(candidate.Rules); err != nil {
+		return nil, fmt.Errorf("rules version %d: %w", candidateVersion, err)
+	}
+
+	report := &LabelDryRunReport{
+		BaseVersion:      base.Version,
+		CandidateVersion: candidate.Version,
+	}
+	diffs := make(map[labelHit]*LabelDiff)
+	diffOf := func(h labelHit) *LabelDiff {
+		d, ok := diffs[h]
+		if !ok {
+			d = &LabelDiff{Category: h.category, Label: h.label}
+			diffs[h] = d
+			report.Diffs = append(report.Diffs, d)
+		}
+		return d
+	}
+
+	var traders []model.Trader
+	err = db.FindInBatches(&traders, 200, func(tx *gorm.DB, batch int) error {
+		for i := range traders {
+			m, err := loadLabelMetrics(db, &traders[i])
+			if err != nil {
+				return err
+			}
+			report.Traders++
+
+			before := hitSet(base.Rules, m)
+			after := hitSet(candidate.Rules, m)
+			changed := false
+			for h := range before {
+				diffOf(h).Before++
+				if _, ok := after[h]; !ok {
+					diffOf(h).Removed++
+					changed = true
+				}
+			}
+			for h := range after {
+				diffOf(h).After++
+				if _, ok := before[h]; !ok {
+					diffOf(h).Added++
+					changed = true
+				}
+			}
+			if changed {
+				report.Changed++
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func hitSet(rules model.LabelRules, m labelMetrics) map[labelHit]struct{} {
+	set := make(map[labelHit]struct{})
+	if m.tradeCount == 0 {
+		return set
+	}
+	for _, h := range matchLabelRules(rules, m) {
+		set[h] = struct{}{}
+	}
+	return set
+}
+
+func loadLabelMetrics(db *gorm.DB, trader *model.Trader) (labelMetrics, error) {
+	aggs, err := loadTradeAggs(db, trader.Address)
+	if err != nil {
+		return labelMetrics{}, fmt.Errorf("load trades %s: %w", trader.Address, err)
+	}
+
+	var av model.TraderAccountValue
+	if err := db.Where("address = ? AND \"window\" = ?", trader.Address, "allTime").
+		Limit(1).Find(&av).Error; err != nil {
+		return labelMetrics{}, fmt.Errorf("load account value %s: %w", trader.Address, err)
+	}
+
+	return calcLabelMetrics(trader, aggs["allTime"], []byte(av.History)), nil
+}
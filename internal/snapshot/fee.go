@@ -0,0 +1,74 @@
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/hypercopy/crawler/internal/utility"
+)
+
+// feeStats 单个窗口的手续费聚合（仅统计 USDC 计价的成交，现货买入以基础币计费的成交不计入）
+type feeStats struct {
+	volume      float64 // 成交额 Σ px*sz
+	feePaid     float64 // 已付手续费 Σ fee (fee > 0)
+	makerRebate float64 // Maker 返佣 Σ -fee (fee < 0)
+	grossPnl    float64 // 毛盈亏 Σ closedPnl（不含手续费）
+}
+
+func (f feeStats) netFee() float64 {
+	return f.feePaid - f.makerRebate
+}
+
+// feeRate 有效费率：净手续费 / 成交额
+func (f feeStats) feeRate() float64 {
+	if f.volume == 0 {
+		return 0
+	}
+	return f.netFee() / f.volume
+}
+
+// feePnlRatio 手续费占毛盈亏比例；毛盈亏不为正时没有意义，返回 0
+func (f feeStats) feePnlRatio() float64 {
+	if f.grossPnl <= 0 {
+		return 0
+	}
+	return f.netFee() / f.grossPnl
+}
+
+// loadFeeStats 在数据库内按窗口聚合 trader_fills，避免把全部成交加载到内存
+func (s *Syncer) loadFeeStats(address string) (map[string]feeStats, error) {
+	var rows []struct {
+		Win         string
+		Volume      float64
+		FeePaid     float64
+		MakerRebate float64
+		GrossPnl    float64
+	}
+
+	err := s.db.Raw(`
+		SELECT w.win,
+			COALESCE(SUM(f.px * f.sz), 0) AS volume,
+			COALESCE(SUM(f.fee) FILTER (WHERE f.fee > 0), 0) AS fee_paid,
+			COALESCE(-SUM(f.fee) FILTER (WHERE f.fee < 0), 0) AS maker_rebate,
+			COALESCE(SUM(f.closed_pnl), 0) AS gross_pnl
+		FROM (VALUES ('day', ?::bigint), ('week', ?::bigint), ('month', ?::bigint), ('allTime', ?::bigint)) AS w(win, cutoff)
+		JOIN trader_fills f ON f.address = ? AND f.time >= w.cutoff AND f.fee_token = 'USDC'
+		GROUP BY w.win`,
+		utility.WindowCutoff("day"), utility.WindowCutoff("week"),
+		utility.WindowCutoff("month"), utility.WindowCutoff("allTime"),
+		address,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("aggregate fills: %w", err)
+	}
+
+	out := make(map[string]feeStats, len(rows))
+	for _, r := range rows {
+		out[r.Win] = feeStats{
+			volume:      r.Volume,
+			feePaid:     r.FeePaid,
+			makerRebate: r.MakerRebate,
+			grossPnl:    r.GrossPnl,
+		}
+	}
+	return out, nil
+}
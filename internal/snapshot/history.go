@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 快照历史保留策略：rawRetention 内保留每一轮，hourlyRetention 内每小时保留一条，更早的每天保留一条
+const (
+	rawRetention    = 2 * 24 * time.Hour
+	hourlyRetention = 90 * 24 * time.Hour
+)
+
+// redisKeyPruneWatermark HASH，raw / hourly -> 上次降采样使用的 rawCutoff / hourlyCutoff（毫秒时间戳）
+const redisKeyPruneWatermark = "snapshot:prune_watermark"
+
+// pruneSnapshotHistory 按保留策略对 trader_snapshot_history 降采样，每个时间桶保留最早的一条。
+// 上次的截止时间之前的行已降采样过，只处理上次截止时间所在的桶之后、本次截止时间之前的行
+func (s *Syncer) pruneSnapshotHistory() error {
+	ctx := context.Background()
+	now := time.Now()
+	rawCutoff := now.Add(-rawRetention).UnixMilli()
+	hourlyCutoff := now.Add(-hourlyRetention).UnixMilli()
+
+	prevRaw, prevHourly, err := s.pruneWatermark(ctx)
+	if err != nil {
+		return fmt.Errorf("load prune watermark: %w", err)
+	}
+	// 从上次截止时间所在桶的起点开始，桶内已保留的那一条参与排序，保证每个桶仍只留一条
+	hourlyFrom := max(prevRaw-prevRaw%msPerHour, hourlyCutoff)
+	dailyFrom := prevHourly - prevHourly%msPerDay
+
+	hourly, err := s.downsampleHistory(hourlyFrom, rawCutoff, msPerHour)
+	if err != nil {
+		return err
+	}
+	daily, err := s.downsampleHistory(dailyFrom, hourlyCutoff, msPerDay)
+	if err != nil {
+		return err
+	}
+	if n := hourly + daily; n > 0 {
+		zap.S().Infof("[snapshot] pruned %d snapshot history rows", n)
+	}
+
+	if err := s.rdb.HSet(ctx, redisKeyPruneWatermark, "raw", rawCutoff, "hourly", hourlyCutoff).Err(); err != nil {
+		zap.S().Errorf("[snapshot] save prune watermark error: %v", err)
+	}
+	return nil
+}
+
+// downsampleHistory 对 [from, to) 内的快照历史按 bucketMs 分桶，每个桶保留最早的一条，返回删除的行数
+func (s *Syncer) downsampleHistory(from, to, bucketMs int64) (int64, error) {
+	if from >= to {
+		return 0, nil
+	}
+	res := s.db.Exec(`
+		DELETE FROM trader_snapshot_history h
+		USING (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY address, time / ? ORDER BY time) AS rn
+			FROM trader_snapshot_history
+			WHERE time >= ? AND time < ?
+		) r
+		WHERE h.id = r.id AND r.rn > 1`,
+		bucketMs, from, to,
+	)
+	if res.Error != nil {
+		return 0, fmt.Errorf("prune snapshot history: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// pruneWatermark 读取上次降采样的截止时间，从未执行过时返回 0（全量处理）
+func (s *Syncer) pruneWatermark(ctx context.Context) (raw, hourly int64, err error) {
+	vals, err := s.rdb.HMGet(ctx, redisKeyPruneWatermark, "raw", "hourly").Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	parse := func(v interface{}) int64 {
+		str, _ := v.(string)
+		n, _ := strconv.ParseInt(str, 10, 64)
+		return n
+	}
+	return parse(vals[0]), parse(vals[1]), nil
+}
@@ -0,0 +1,147 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/events"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const labelChangeChannel = "trader_label_change"
+
+// LabelChangeEvent 交易员标签变更事件，发布到 labelChangeChannel
+type LabelChangeEvent struct {
+	Address     string             `json:"address"`
+	Added       []string           `json:"added"`
+	Removed     []string           `json:"removed"`
+	Labels      []string           `json:"labels"`
+	RuleVersion int                `json:"ruleVersion"`
+	Metrics     map[string]float64 `json:"metrics"`
+	Time        int64              `json:"time"`
+}
+
+// saveLabels 在一个事务中写入交易员标签、标签指标、updates 中的其他 traders 列及标签变更记录，
+// inTx 非空时在同一事务中执行；提交后发布标签变更事件
+func (s *Syncer) saveLabels(trader *model.Trader, labels []string, m labelMetrics,
+	updates map[string]interface{}, inTx func(tx *gorm.DB) error) error {
+	if labels == nil {
+		labels = []string{}
+	}
+	metricsJSON, err := json.Marshal(m.snapshot())
+	if err != nil {
+		return fmt.Errorf("marshal metrics: %w", err)
+	}
+	if updates == nil {
+		updates = make(map[string]interface{}, 2)
+	}
+	updates["labels"] = pq.StringArray(labels)
+	updates["label_metrics"] = datatypes.JSON(metricsJSON)
+
+	oldLabels := []string(trader.Labels)
+	var evt *LabelChangeEvent
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if inTx != nil {
+			if err := inTx(tx); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(trader).Updates(updates).Error; err != nil {
+			return fmt.Errorf("update labels: %w", err)
+		}
+		var err error
+		evt, err = s.recordLabelChanges(tx, trader.Address, oldLabels, labels, m)
+		if err != nil {
+			return fmt.Errorf("record label changes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if evt != nil {
+		s.publishLabelChange(evt)
+	}
+	return nil
+}
+
+// recordLabelChanges 对比新旧标签，在 tx 中写入 trader_label_history；
+// 返回待发布的变更事件，标签无变化时返回 nil，由调用方在事务提交后调用 publishLabelChange
+func (s *Syncer) recordLabelChanges(tx *gorm.DB, address string, oldLabels, newLabels []string, m labelMetrics) (*LabelChangeEvent, error) {
+	added := diffLabels(newLabels, oldLabels)
+	removed := diffLabels(oldLabels, newLabels)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil, nil
+	}
+
+	ruleVersion := 0
+	if set := s.labelRules.Load(); set != nil {
+		ruleVersion = set.Version
+	}
+	metrics := m.snapshot()
+	metricsJSON, err := json.Marshal(metrics)
+	if err != nil {
+		return nil, fmt.Errorf("marshal metrics: %w", err)
+	}
+
+	records := make([]model.TraderLabelHistory, 0, len(added)+len(removed))
+	for _, l := range added {
+		records = append(records, model.TraderLabelHistory{
+			Address:     address,
+			Label:       l,
+			Action:      model.LabelActionAdded,
+			RuleVersion: ruleVersion,
+			Metrics:     metricsJSON,
+		})
+	}
+	for _, l := range removed {
+		records = append(records, model.TraderLabelHistory{
+			Address:     address,
+			Label:       l,
+			Action:      model.LabelActionRemoved,
+			RuleVersion: ruleVersion,
+			Metrics:     metricsJSON,
+		})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, fmt.Errorf("insert label history: %w", err)
+	}
+
+	return &LabelChangeEvent{
+		Address:     address,
+		Added:       added,
+		Removed:     removed,
+		Labels:      newLabels,
+		RuleVersion: ruleVersion,
+		Metrics:     metrics,
+		Time:        time.Now().UnixMilli(),
+	}, nil
+}
+
+// publishLabelChange 发布标签变更事件
+func (s *Syncer) publishLabelChange(evt *LabelChangeEvent) {
+	if err := s.bus.Publish(context.Background(), labelChangeChannel, events.LabelChange, evt); err != nil {
+		zap.S().Errorf("[snapshot] redis publish label change error: %v", err)
+	}
+}
+
+// diffLabels 返回在 a 中但不在 b 中的标签
+func diffLabels(a, b []string) []string {
+	set := make(map[string]struct{}, len(b))
+	for _, l := range b {
+		set[l] = struct{}{}
+	}
+	var out []string
+	for _, l := range a {
+		if _, ok := set[l]; !ok {
+			out = append(out, l)
+		}
+	}
+	return out
+}
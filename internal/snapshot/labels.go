@@ -4,7 +4,6 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/hypercopy/crawler/internal/consts"
 	"github.com/hypercopy/crawler/internal/model"
 )
 
@@ -35,35 +34,14 @@ type labelMetrics struct {
 	hasSeries90     bool
 }
 
-func computeLabels(trader *model.Trader, trades []model.CompletedTrade, accountValueHistory []byte) []string {
-	m := calcLabelMetrics(trader, trades, accountValueHistory)
+func computeLabels(rules model.LabelRules, m labelMetrics) []string {
 	if m.tradeCount == 0 {
 		return nil
 	}
-
-	var labels []string
-
-	if l := accountSizeLabel(m); l != "" {
-		labels = append(labels, l)
-	}
-	if l := tradingRhythmLabel(m); l != "" {
-		labels = append(labels, l)
-	}
-	if l := profitStatusLabel(m); l != "" {
-		labels = append(labels, l)
-	}
-	if l := directionLabel(m); l != "" {
-		labels = append(labels, l)
-	}
-	labels = append(labels, tradingStyleLabels(m)...)
-	if l := profitScaleLabel(m); l != "" {
-		labels = append(labels, l)
-	}
-
-	return labels
+	return evalLabelRules(rules, m)
 }
 
-func calcLabelMetrics(trader *model.Trader, trades []model.CompletedTrade, accountValueHistory []byte) labelMetrics {
+func calcLabelMetrics(trader *model.Trader, agg tradeAgg, accountValueHistory []byte) labelMetrics {
 	var m labelMetrics
 
 	tv, _ := strconv.ParseFloat(trader.SnapTotalValue, 64)
@@ -119,72 +97,36 @@ func calcLabelMetrics(trader *model.Trader, trades []model.CompletedTrade, accou
 		}
 	}
 
-	// ── trade-level metrics ─────────────────────────────────────────
-	cutoff90Ms := now.Add(-90 * 24 * time.Hour).UnixMilli()
-	cutoff30Ms := now.Add(-30 * 24 * time.Hour).UnixMilli()
-
-	var (
-		totalHoldingMs float64
-		totalProfit    float64
-		totalLoss      float64
-		winCount       int
-		lossCount      int
-		longCount      int
-		activeDaySet   = make(map[string]struct{})
-	)
-
-	for _, t := range trades {
-		m.tradeCount++
-		totalHoldingMs += float64(t.EndTime - t.StartTime)
-
-		if t.Pnl > 0 {
-			winCount++
-			totalProfit += t.Pnl
-		} else if t.Pnl < 0 {
-			lossCount++
-			totalLoss += -t.Pnl
-		}
-
-		if t.Direction == "long" {
-			longCount++
-		}
-
-		if t.EndTime >= cutoff90Ms {
-			m.tradeCount90++
-			day := time.UnixMilli(t.EndTime).Format("2006-01-02")
-			activeDaySet[day] = struct{}{}
-		}
-		if t.EndTime >= cutoff30Ms {
-			m.tradeCount30++
-		}
-	}
-
-	m.activeDays90 = len(activeDaySet)
+	// ── trade-level metrics（由 SQL 聚合得到）────────────────────────
+	m.tradeCount = agg.tradeCount
+	m.tradeCount90 = agg.tradeCount90
+	m.tradeCount30 = agg.tradeCount30
+	m.activeDays90 = agg.activeDays90
 
 	if m.tradeCount > 0 {
-		m.avgHoldingMs = totalHoldingMs / float64(m.tradeCount)
-		m.winRate = float64(winCount) / float64(m.tradeCount)
-		m.longRatio = float64(longCount) / float64(m.tradeCount)
-		m.expectedValue = (totalProfit - totalLoss) / float64(m.tradeCount)
+		m.avgHoldingMs = agg.holdingMs / float64(m.tradeCount)
+		m.winRate = float64(agg.profitCount) / float64(m.tradeCount)
+		m.longRatio = float64(agg.longCount) / float64(m.tradeCount)
+		m.expectedValue = (agg.totalProfit - agg.totalLoss) / float64(m.tradeCount)
 	}
 
-	if totalLoss > 0 {
-		m.profitFactor = totalProfit / totalLoss
-	} else if totalProfit > 0 {
+	if agg.totalLoss > 0 {
+		m.profitFactor = agg.totalProfit / agg.totalLoss
+	} else if agg.totalProfit > 0 {
 		m.profitFactor = 999
 	}
 
-	if winCount > 0 {
-		m.avgProfitPerWin = totalProfit / float64(winCount)
+	if agg.profitCount > 0 {
+		m.avgProfitPerWin = agg.totalProfit / float64(agg.profitCount)
 	}
 
 	avgLoss := 0.0
-	if lossCount > 0 {
-		avgLoss = totalLoss / float64(lossCount)
+	if agg.lossCount > 0 {
+		avgLoss = agg.totalLoss / float64(agg.lossCount)
 	}
-	if avgLoss > 0 && winCount > 0 {
+	if avgLoss > 0 && agg.profitCount > 0 {
 		m.riskRewardRatio = m.avgProfitPerWin / avgLoss
-	} else if winCount > 0 {
+	} else if agg.profitCount > 0 {
 		m.riskRewardRatio = 999
 	}
 
@@ -193,117 +135,64 @@ func calcLabelMetrics(trader *model.Trader, trades []model.CompletedTrade, accou
 
 // ── label assignment ────────────────────────────────────────────────
 
-func accountSizeLabel(m labelMetrics) string {
-	switch {
-	case m.totalValue > 500_000:
-		return consts.AccountTotalValueWhale
-	case m.totalValue >= 100_000:
-		return consts.AccountTotalValueMedium
-	case m.totalValue > 0:
-		return consts.AccountTotalValueSmall
-	default:
-		return ""
-	}
-}
-
-func tradingRhythmLabel(m labelMetrics) string {
-	switch {
-	case m.avgHoldingMs > msPerWeek:
-		return consts.TradingRhythmLongTerm
-	case m.avgHoldingMs > msPerDay:
-		return consts.TradingRhythmSwing
-	case m.avgHoldingMs >= msPerHour:
-		return consts.TradingRhythmShortTerm
-	default:
-		return consts.TradingRhythmScalping
-	}
-}
-
-func profitStatusLabel(m labelMetrics) string {
-	roiPct := m.totalROI * 100
-
-	if roiPct >= -5 && roiPct <= 5 {
-		return consts.ProfitStatusBreakeven
-	}
-
-	if m.activeDays90 >= 30 && m.tradeCount90 >= 30 && roiPct > 5 {
-		if m.profitFactor >= 1.3 && m.expectedValue > 0 && m.rolling30Return > 0 {
-			return consts.ProfitStatusConsistent
+// labelMetricDefs 规则条件可引用的全部指标：按名称取值，以及从标签指标快照还原（派生指标 set 为 nil）
+var labelMetricDefs = []struct {
+	name  string
+	value func(m labelMetrics) float64
+	set   func(m *labelMetrics, v float64)
+}{
+	{"total_value", func(m labelMetrics) float64 { return m.totalValue }, func(m *labelMetrics, v float64) { m.totalValue = v }},
+	{"avg_holding_ms", func(m labelMetrics) float64 { return m.avgHoldingMs }, func(m *labelMetrics, v float64) { m.avgHoldingMs = v }},
+	{"avg_holding_days", func(m labelMetrics) float64 { return m.avgHoldingMs / msPerDay }, nil},
+	{"active_days_90", func(m labelMetrics) float64 { return float64(m.activeDays90) }, func(m *labelMetrics, v float64) { m.activeDays90 = int(v) }},
+	{"trade_count", func(m labelMetrics) float64 { return float64(m.tradeCount) }, func(m *labelMetrics, v float64) { m.tradeCount = int(v) }},
+	{"trade_count_90", func(m labelMetrics) float64 { return float64(m.tradeCount90) }, func(m *labelMetrics, v float64) { m.tradeCount90 = int(v) }},
+	{"trade_count_30", func(m labelMetrics) float64 { return float64(m.tradeCount30) }, func(m *labelMetrics, v float64) { m.tradeCount30 = int(v) }},
+	{"total_roi_pct", func(m labelMetrics) float64 { return m.totalROI * 100 }, func(m *labelMetrics, v float64) { m.totalROI = v / 100 }},
+	{"profit_factor", func(m labelMetrics) float64 { return m.profitFactor }, func(m *labelMetrics, v float64) { m.profitFactor = v }},
+	{"expectancy", func(m labelMetrics) float64 { return m.expectedValue }, func(m *labelMetrics, v float64) { m.expectedValue = v }},
+	{"rolling_30d_return", func(m labelMetrics) float64 { return m.rolling30Return }, func(m *labelMetrics, v float64) { m.rolling30Return = v }},
+	{"long_pct", func(m labelMetrics) float64 { return m.longRatio * 100 }, func(m *labelMetrics, v float64) { m.longRatio = v / 100 }},
+	{"win_rate", func(m labelMetrics) float64 { return m.winRate }, func(m *labelMetrics, v float64) { m.winRate = v }},
+	{"risk_reward_ratio", func(m labelMetrics) float64 { return m.riskRewardRatio }, func(m *labelMetrics, v float64) { m.riskRewardRatio = v }},
+	{"max_drawdown", func(m labelMetrics) float64 { return m.maxDrawdown }, func(m *labelMetrics, v float64) { m.maxDrawdown = v }},
+	{"max_drawdown_90", func(m labelMetrics) float64 { return m.maxDrawdown90 }, func(m *labelMetrics, v float64) { m.maxDrawdown90 = v }},
+	{"sharpe", func(m labelMetrics) float64 { return m.sharpe }, func(m *labelMetrics, v float64) { m.sharpe = v }},
+	{"avg_profit_per_win", func(m labelMetrics) float64 { return m.avgProfitPerWin }, func(m *labelMetrics, v float64) { m.avgProfitPerWin = v }},
+	{"has_series_90", func(m labelMetrics) float64 {
+		if m.hasSeries90 {
+			return 1
 		}
-		if m.profitFactor < 1.3 || m.rolling30Return <= 0 {
-			return consts.ProfitStatusVolatile
-		}
-	}
-
-	return ""
+		return 0
+	}, func(m *labelMetrics, v float64) { m.hasSeries90 = v != 0 }},
 }
 
-func directionLabel(m labelMetrics) string {
-	longPct := m.longRatio * 100
-	switch {
-	case longPct < 30:
-		return consts.DirectionPreferenceBearish
-	case longPct > 70:
-		return consts.DirectionPreferenceBullish
-	default:
-		return consts.DirectionPreferenceNeutral
+// snapshot 导出全部指标，用于标签变更记录
+func (m labelMetrics) snapshot() map[string]float64 {
+	out := make(map[string]float64, len(labelMetricDefs))
+	for _, d := range labelMetricDefs {
+		out[d.name] = d.value(m)
 	}
+	return out
 }
 
-func tradingStyleLabels(m labelMetrics) []string {
-	var labels []string
-	avgHoldDays := m.avgHoldingMs / msPerDay
-
-	if avgHoldDays <= 1 && m.winRate > 0.6 &&
-		m.riskRewardRatio >= 1.2 && m.profitFactor >= 1.2 && m.tradeCount30 >= 20 {
-		labels = append(labels, consts.TradingStyleHFSteady)
-	}
-
-	if avgHoldDays <= 1 && m.winRate < 0.5 &&
-		m.riskRewardRatio >= 5 && m.profitFactor >= 1.5 && m.tradeCount30 >= 20 {
-		labels = append(labels, consts.TradingStyleHFAggressive)
-	}
-
-	if avgHoldDays > 7 && m.winRate > 0.6 &&
-		m.riskRewardRatio >= 1.2 && m.profitFactor >= 1.2 && m.tradeCount30 <= 10 {
-		labels = append(labels, consts.TradingStyleLFSteady)
-	}
-
-	if m.winRate >= 0.6 && m.riskRewardRatio >= 1.5 &&
-		m.maxDrawdown <= 0.25 && m.profitFactor >= 1.5 && m.sharpe >= 1.0 {
-		labels = append(labels, consts.TradingStyleStableProfit)
-	}
-
-	if m.riskRewardRatio >= 3 && m.avgProfitPerWin >= 10_000 && m.maxDrawdown >= 0.3 {
-		labels = append(labels, consts.TradingStyleHighRiskReward)
-	}
-
-	if m.winRate < 0.5 && m.riskRewardRatio >= 5 &&
-		m.profitFactor >= 1.5 && m.tradeCount30 <= 20 {
-		labels = append(labels, consts.TradingStyleAsymmetric)
-	}
-
-	if m.hasSeries90 && m.maxDrawdown90 <= 0.2 {
-		labels = append(labels, consts.TradingStyleLowDrawdown)
-	}
-
-	if m.sharpe < 0.8 && m.profitFactor >= 1.0 {
-		labels = append(labels, consts.TradingStyleVolatility)
+// labelMetricsFromSnapshot 由 snapshot 导出的指标还原 labelMetrics
+func labelMetricsFromSnapshot(vals map[string]float64) labelMetrics {
+	var m labelMetrics
+	for _, d := range labelMetricDefs {
+		if d.set != nil {
+			d.set(&m, vals[d.name])
+		}
 	}
-
-	return labels
+	return m
 }
 
-func profitScaleLabel(m labelMetrics) string {
-	if m.avgProfitPerWin == 0 {
-		return ""
-	}
-	switch {
-	case m.avgProfitPerWin > 50_000:
-		return consts.ProfitScaleLarge
-	case m.avgProfitPerWin >= 3_000:
-		return consts.ProfitScaleMedium
-	default:
-		return consts.ProfitScaleSmall
+// metric 按名称取指标值，供 label_rule_set 中的规则条件引用
+func (m labelMetrics) metric(name string) (float64, bool) {
+	for _, d := range labelMetricDefs {
+		if d.name == name {
+			return d.value(m), true
+		}
 	}
+	return 0, false
 }
@@ -0,0 +1,56 @@
+package snapshot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+// percentileChunk 每个 jsonb_build_object 包含的指标数；PostgreSQL 函数最多 100 个参数（每个指标占 2 个），
+// 指标分组构建后再用 || 合并
+const percentileChunk = 40
+
+// updatePercentiles 用 percent_rank() 计算每个指标在同窗口全体交易员、以及同账户规模分组内的百分位，
+// 写回 trader_statistics.percentiles / bucket_percentiles。回撤、手续费等越小越好的指标按降序排名，
+// 使百分位越高均表示越优于同组交易员
+func (s *Syncer) updatePercentiles() error {
+	res := s.db.Exec(percentileSQL())
+	if res.Error != nil {
+		return fmt.Errorf("update percentiles: %w", res.Error)
+	}
+	return nil
+}
+
+func percentileSQL() string {
+	return `
+		UPDATE trader_statistics s
+		SET percentiles = r.pct, bucket_percentiles = r.bucket_pct
+		FROM (
+			SELECT id,
+				` + percentileObject(`"window"`) + ` AS pct,
+				` + percentileObject(`"window", size_bucket`) + ` AS bucket_pct
+			FROM trader_statistics
+		) r
+		WHERE s.id = r.id`
+}
+
+// percentileObject 生成按 partition 分区计算全部指标百分位的 jsonb 表达式
+func percentileObject(partition string) string {
+	var objs []string
+	for i := 0; i < len(model.PercentileMetrics); i += percentileChunk {
+		chunk := model.PercentileMetrics[i:min(i+percentileChunk, len(model.PercentileMetrics))]
+		pairs := make([]string, 0, len(chunk))
+		for _, m := range chunk {
+			order := "ASC"
+			if _, ok := model.LowerIsBetterMetrics[m]; ok {
+				order = "DESC"
+			}
+			pairs = append(pairs, fmt.Sprintf(
+				`'%s', round(percent_rank() OVER (PARTITION BY %s ORDER BY COALESCE(%s, 0) %s)::numeric, 4)`,
+				m, partition, m, order))
+		}
+		objs = append(objs, "jsonb_build_object("+strings.Join(pairs, ", ")+")")
+	}
+	return strings.Join(objs, " || ")
+}
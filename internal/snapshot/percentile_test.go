@@ -0,0 +1,62 @@
+package snapshot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+func TestPercentileObject(t *testing.T) {
+	sql := percentileObject(`"window"`)
+
+	wantChunks := (len(model.PercentileMetrics) + percentileChunk - 1) / percentileChunk
+	if got := strings.Count(sql, "jsonb_build_object("); got != wantChunks {
+		t.Errorf("jsonb_build_object count = %d, want %d", got, wantChunks)
+	}
+	tests := []struct {
+		metric string
+		order  string
+	}{
+		{"sharpe", "ASC"},
+		{"win_rate", "ASC"},
+		{"total_pnl", "ASC"},
+		{"drawdown", "DESC"},
+		{"drawdown90", "DESC"},
+		{"fee_rate", "DESC"},
+		{"fee_pnl_ratio", "DESC"},
+	}
+	for _, tt := range tests {
+		want := fmt.Sprintf(`'%s', round(percent_rank() OVER (PARTITION BY "window" ORDER BY COALESCE(%s, 0) %s)::numeric, 4)`,
+			tt.metric, tt.metric, tt.order)
+		if !strings.Contains(sql, want) {
+			t.Errorf("percentileObject() missing %s", want)
+		}
+	}
+	for _, m := range model.PercentileMetrics {
+		if got := strings.Count(sql, "'"+m+"', "); got != 1 {
+			t.Errorf("metric %s appears %d times, want 1", m, got)
+		}
+	}
+}
+
+func TestPercentileMetrics(t *testing.T) {
+	seen := make(map[string]bool, len(model.PercentileMetrics))
+	for _, m := range model.PercentileMetrics {
+		if seen[m] {
+			t.Errorf("duplicate percentile metric %s", m)
+		}
+		seen[m] = true
+	}
+	// 每个 jsonb_build_object 最多 100 个参数
+	if percentileChunk*2 > 100 {
+		t.Errorf("percentileChunk %d exceeds the 100 argument limit", percentileChunk)
+	}
+	for m := range model.LowerIsBetterMetrics {
+		if !slices.Contains(model.PercentileMetrics, m) {
+			t.Errorf("lower-is-better metric %s is not a percentile metric", m)
+		}
+	}
+}
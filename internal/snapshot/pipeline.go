@@ -0,0 +1,90 @@
+package snapshot
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter 全局请求速率限制：所有拉取 worker 共享同一个 ticker，每个 tick 放行一次请求
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+// newRateLimiter rps <= 0 时不限速
+func newRateLimiter(rps float64) *rateLimiter {
+	if rps <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / rps))}
+}
+
+func (l *rateLimiter) wait() {
+	if l.ticker != nil {
+		<-l.ticker.C
+	}
+}
+
+// 统计队列中地址的状态
+const (
+	statsQueued = iota + 1
+	statsRunning
+	statsRerun // 计算期间又有新快照写入，完成后需要再算一次
+)
+
+// statsQueue 拉取阶段与统计阶段之间的 FIFO 队列；同一地址同时最多排队一次、计算一次
+type statsQueue struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	list  []string
+	state map[string]int
+}
+
+func newStatsQueue() *statsQueue {
+	q := &statsQueue{state: make(map[string]int)}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *statsQueue) push(addr string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch q.state[addr] {
+	case 0:
+		q.state[addr] = statsQueued
+		q.list = append(q.list, addr)
+		q.cond.Signal()
+	case statsRunning:
+		q.state[addr] = statsRerun
+	}
+}
+
+// pop 阻塞直到有待计算的地址
+func (q *statsQueue) pop() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.list) == 0 {
+		q.cond.Wait()
+	}
+	addr := q.list[0]
+	q.list = q.list[1:]
+	q.state[addr] = statsRunning
+	return addr
+}
+
+func (q *statsQueue) done(addr string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.state[addr] == statsRerun {
+		q.state[addr] = statsQueued
+		q.list = append(q.list, addr)
+		q.cond.Signal()
+		return
+	}
+	delete(q.state, addr)
+}
+
+func (q *statsQueue) size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.list)
+}
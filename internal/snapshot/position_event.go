@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+// diffPositions 对比同一交易员前后两次持仓快照，生成持仓变更事件。
+// 同一币种可能同时产生多个事件（如加仓同时调整杠杆）；before 中没有的币种记为 opened。
+// 交易员首次同步快照时 upsertPositions 不做对比，已有持仓不会被误记为新开仓。
+func diffPositions(address string, before, after []model.TraderPosition, nowMs int64) []model.TraderPositionEvent {
+	oldByCoin := make(map[string]*model.TraderPosition, len(before))
+	for i := range before {
+		oldByCoin[before[i].Coin] = &before[i]
+	}
+	newByCoin := make(map[string]*model.TraderPosition, len(after))
+	for i := range after {
+		newByCoin[after[i].Coin] = &after[i]
+	}
+
+	coinSet := make(map[string]struct{}, len(oldByCoin)+len(newByCoin))
+	for c := range oldByCoin {
+		coinSet[c] = struct{}{}
+	}
+	for c := range newByCoin {
+		coinSet[c] = struct{}{}
+	}
+	coins := make([]string, 0, len(coinSet))
+	for c := range coinSet {
+		coins = append(coins, c)
+	}
+	sort.Strings(coins)
+
+	var events []model.TraderPositionEvent
+	for _, coin := range coins {
+		o, n := oldByCoin[coin], newByCoin[coin]
+		event := func(eventType string) model.TraderPositionEvent {
+			e := model.TraderPositionEvent{Address: address, Coin: coin, Time: nowMs, EventType: eventType}
+			if o != nil {
+				e.BeforeSzi = o.Szi
+				e.BeforeEntryPx = o.EntryPx
+				e.BeforePositionValue = o.PositionValue
+				e.BeforeLeverage = o.Leverage
+				e.BeforeLeverageType = o.LeverageType
+				e.BeforeUnrealizedPnl = o.UnrealizedPnl
+			}
+			if n != nil {
+				e.AfterSzi = n.Szi
+				e.AfterEntryPx = n.EntryPx
+				e.AfterPositionValue = n.PositionValue
+				e.AfterLeverage = n.Leverage
+				e.AfterLeverageType = n.LeverageType
+				e.AfterUnrealizedPnl = n.UnrealizedPnl
+			}
+			return e
+		}
+
+		switch {
+		case o == nil:
+			events = append(events, event(model.PositionEventOpened))
+			continue
+		case n == nil:
+			events = append(events, event(model.PositionEventClosed))
+			continue
+		}
+
+		oldSzi, _ := strconv.ParseFloat(o.Szi, 64)
+		newSzi, _ := strconv.ParseFloat(n.Szi, 64)
+		switch {
+		case oldSzi*newSzi < 0:
+			events = append(events, event(model.PositionEventFlipped))
+		case math.Abs(newSzi) > math.Abs(oldSzi):
+			events = append(events, event(model.PositionEventIncreased))
+		case math.Abs(newSzi) < math.Abs(oldSzi):
+			events = append(events, event(model.PositionEventDecreased))
+		}
+		if o.Leverage != n.Leverage {
+			events = append(events, event(model.PositionEventLeverageChanged))
+		}
+		if o.LeverageType != n.LeverageType {
+			events = append(events, event(model.PositionEventMarginModeChanged))
+		}
+	}
+	return events
+}
@@ -0,0 +1,97 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+func traderPosition(coin, szi string, leverage int, leverageType string) model.TraderPosition {
+	return model.TraderPosition{Coin: coin, Szi: szi, Leverage: leverage, LeverageType: leverageType}
+}
+
+func TestDiffPositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []model.TraderPosition
+		after  []model.TraderPosition
+		want   []string // "币种:事件类型"，按币种排序
+	}{
+		{"no positions", nil, nil, nil},
+		{
+			name:   "unchanged",
+			before: []model.TraderPosition{traderPosition("BTC", "1", 10, "cross")},
+			after:  []model.TraderPosition{traderPosition("BTC", "1", 10, "cross")},
+		},
+		{
+			name:  "opened",
+			after: []model.TraderPosition{traderPosition("ETH", "-2", 5, "cross")},
+			want:  []string{"ETH:" + model.PositionEventOpened},
+		},
+		{
+			name:   "closed",
+			before: []model.TraderPosition{traderPosition("ETH", "-2", 5, "cross")},
+			want:   []string{"ETH:" + model.PositionEventClosed},
+		},
+		{
+			name:   "flipped",
+			before: []model.TraderPosition{traderPosition("BTC", "1", 10, "cross")},
+			after:  []model.TraderPosition{traderPosition("BTC", "-3", 10, "cross")},
+			want:   []string{"BTC:" + model.PositionEventFlipped},
+		},
+		{
+			name:   "increased and decreased",
+			before: []model.TraderPosition{traderPosition("BTC", "1", 10, "cross"), traderPosition("ETH", "-4", 5, "cross")},
+			after:  []model.TraderPosition{traderPosition("BTC", "1.5", 10, "cross"), traderPosition("ETH", "-3", 5, "cross")},
+			want:   []string{"BTC:" + model.PositionEventIncreased, "ETH:" + model.PositionEventDecreased},
+		},
+		{
+			name:   "resize with leverage and margin mode change",
+			before: []model.TraderPosition{traderPosition("SOL", "10", 3, "cross")},
+			after:  []model.TraderPosition{traderPosition("SOL", "20", 5, "isolated")},
+			want: []string{
+				"SOL:" + model.PositionEventIncreased,
+				"SOL:" + model.PositionEventLeverageChanged,
+				"SOL:" + model.PositionEventMarginModeChanged,
+			},
+		},
+		{
+			name:   "mixed coins sorted",
+			before: []model.TraderPosition{traderPosition("SOL", "10", 3, "cross"), traderPosition("BTC", "1", 10, "cross")},
+			after:  []model.TraderPosition{traderPosition("ETH", "2", 5, "cross"), traderPosition("BTC", "1", 10, "cross")},
+			want:   []string{"ETH:" + model.PositionEventOpened, "SOL:" + model.PositionEventClosed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := diffPositions("0xabc", tt.before, tt.after, 1000)
+			var got []string
+			for _, e := range events {
+				if e.Address != "0xabc" || e.Time != 1000 {
+					t.Errorf("event %+v has wrong address or time", e)
+				}
+				got = append(got, e.Coin+":"+e.EventType)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffPositions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffPositionsBeforeAfter(t *testing.T) {
+	before := []model.TraderPosition{{Coin: "BTC", Szi: "1", EntryPx: "50000", PositionValue: "50000", Leverage: 10}}
+	after := []model.TraderPosition{{Coin: "BTC", Szi: "-1", EntryPx: "51000", PositionValue: "51000", Leverage: 10}}
+	events := diffPositions("0xabc", before, after, 1)
+	if len(events) != 1 {
+		t.Fatalf("diffPositions() = %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.BeforeSzi != "1" || e.AfterSzi != "-1" || e.BeforeEntryPx != "50000" || e.AfterEntryPx != "51000" {
+		t.Errorf("flipped event = %+v", e)
+	}
+	if opened := diffPositions("0xabc", nil, after, 1); opened[0].BeforeSzi != "" || opened[0].AfterSzi != "-1" {
+		t.Errorf("opened event = %+v", opened[0])
+	}
+}
@@ -0,0 +1,148 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/events"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"go.uber.org/zap"
+)
+
+const (
+	positioningKey     = "smart_money:positioning" // hash: coin -> 最新 CoinPositioning JSON
+	positioningChannel = "smart_money_positioning"
+)
+
+// updatePositioning 按当前 trader_positions 计算各币种的聪明钱持仓指数，追加到 coin_positioning 并推送 Redis。
+// 评分加权使用 allTime 窗口的综合评分（0~100 归一化为权重），未评分的交易员权重为 0。
+func (s *Syncer) updatePositioning() error {
+	var rows []struct {
+		Coin              string
+		LongNotional      float64
+		ShortNotional     float64
+		LongHolders       int
+		ShortHolders      int
+		LongSize          float64
+		ShortSize         float64
+		LongEntryCost     float64
+		ShortEntryCost    float64
+		WeightedNet       float64
+		WeightedTotal     float64
+		ProfitableNet     float64
+		ProfitableHolders int
+	}
+	err := s.db.Raw(`
+		SELECT p.coin,
+			COALESCE(SUM(ABS(p.position_value)) FILTER (WHERE p.szi > 0), 0) AS long_notional,
+			COALESCE(SUM(ABS(p.position_value)) FILTER (WHERE p.szi < 0), 0) AS short_notional,
+			COUNT(*) FILTER (WHERE p.szi > 0) AS long_holders,
+			COUNT(*) FILTER (WHERE p.szi < 0) AS short_holders,
+			COALESCE(SUM(p.szi) FILTER (WHERE p.szi > 0), 0) AS long_size,
+			COALESCE(-SUM(p.szi) FILTER (WHERE p.szi < 0), 0) AS short_size,
+			COALESCE(SUM(p.entry_px * p.szi) FILTER (WHERE p.szi > 0), 0) AS long_entry_cost,
+			COALESCE(-SUM(p.entry_px * p.szi) FILTER (WHERE p.szi < 0), 0) AS short_entry_cost,
+			COALESCE(SUM(COALESCE(sc.score, 0) / 100 * SIGN(p.szi) * ABS(p.position_value)), 0) AS weighted_net,
+			COALESCE(SUM(COALESCE(sc.score, 0) / 100 * ABS(p.position_value)), 0) AS weighted_total,
+			COALESCE(SUM(SIGN(p.szi) * ABS(p.position_value)) FILTER (WHERE st.total_realized_pnl > 0), 0) AS profitable_net,
+			COUNT(*) FILTER (WHERE st.total_realized_pnl > 0) AS profitable_holders
+		FROM trader_positions p
+		LEFT JOIN trader_scores sc ON sc.address = p.address AND sc."window" = 'allTime'
+		LEFT JOIN trader_statistics st ON st.address = p.address AND st."window" = 'allTime'
+		WHERE p.szi <> 0
+		GROUP BY p.coin`).Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("aggregate positioning: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	records := make([]model.CoinPositioning, 0, len(rows))
+	for _, r := range rows {
+		var holderRatio, markPx, avgLong, avgShort, longVsMark, shortVsMark, weightedNet float64
+		if total := r.LongHolders + r.ShortHolders; total > 0 {
+			holderRatio = float64(r.LongHolders) / float64(total)
+		}
+		if size := r.LongSize + r.ShortSize; size > 0 {
+			markPx = (r.LongNotional + r.ShortNotional) / size
+		}
+		if r.LongSize > 0 {
+			avgLong = r.LongEntryCost / r.LongSize
+		}
+		if r.ShortSize > 0 {
+			avgShort = r.ShortEntryCost / r.ShortSize
+		}
+		if avgLong > 0 && markPx > 0 {
+			longVsMark = (markPx - avgLong) / avgLong * 100
+		}
+		if avgShort > 0 && markPx > 0 {
+			shortVsMark = (avgShort - markPx) / avgShort * 100
+		}
+		if r.WeightedTotal > 0 {
+			weightedNet = r.WeightedNet / r.WeightedTotal
+		}
+
+		records = append(records, model.CoinPositioning{
+			Coin:                  r.Coin,
+			Time:                  now.UnixMilli(),
+			LongNotional:          utility.FmtFloat(r.LongNotional),
+			ShortNotional:         utility.FmtFloat(r.ShortNotional),
+			NetNotional:           utility.FmtFloat(r.LongNotional - r.ShortNotional),
+			LongHolders:           r.LongHolders,
+			ShortHolders:          r.ShortHolders,
+			LongHolderRatio:       utility.FmtFloat(holderRatio),
+			MarkPx:                utility.FmtFloat(markPx),
+			AvgLongEntryPx:        utility.FmtFloat(avgLong),
+			AvgShortEntryPx:       utility.FmtFloat(avgShort),
+			LongEntryVsMarkPct:    utility.FmtFloat(longVsMark),
+			ShortEntryVsMarkPct:   utility.FmtFloat(shortVsMark),
+			ScoreWeightedNetRatio: utility.FmtFloat(weightedNet),
+			ProfitableNetNotional: utility.FmtFloat(r.ProfitableNet),
+			ProfitableHolders:     r.ProfitableHolders,
+		})
+	}
+
+	if err := s.db.CreateInBatches(records, 500).Error; err != nil {
+		return fmt.Errorf("save positioning: %w", err)
+	}
+	if err := s.publishPositioning(records); err != nil {
+		return fmt.Errorf("publish positioning: %w", err)
+	}
+	zap.S().Infof("[snapshot] positioning: %d coins", len(records))
+	return nil
+}
+
+func (s *Syncer) publishPositioning(records []model.CoinPositioning) error {
+	ctx := context.Background()
+	fields := make(map[string]interface{}, len(records))
+	for _, r := range records {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		fields[r.Coin] = string(b)
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, positioningKey)
+	pipe.HSet(ctx, positioningKey, fields)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	return s.bus.Publish(ctx, positioningChannel, events.SmartMoneyPositioning, records)
+}
+
+// prunePositioning 删除超过保留期（-positioning-retention）的持仓指数
+func (s *Syncer) prunePositioning() error {
+	if s.positioningRetention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-s.positioningRetention).UnixMilli()
+	return s.db.Where("time < ?", cutoff).Delete(&model.CoinPositioning{}).Error
+}
@@ -0,0 +1,87 @@
+package snapshot
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+// positionRisk 单个持仓的风险指标
+type positionRisk struct {
+	markPx         float64  // 标记价 = 持仓价值 / |仓位|
+	liqDistancePct *float64 // 标记价距清算价还需不利波动的百分比；无清算价（如低杠杆全仓）时为 nil
+	concentration  float64  // 该仓位名义价值占全部持仓名义价值的比例
+}
+
+// traderRisk 交易员整体风险指标
+type traderRisk struct {
+	weightedLeverage     float64  // 按名义价值加权的杠杆倍数
+	maxConcentration     float64  // 最大单仓集中度
+	maxConcentrationCoin string   // 最大单仓币种
+	minLiqDistancePct    *float64 // 距清算最近的持仓的距离百分比
+	minLiqDistanceCoin   string   // 距清算最近的持仓币种
+	crossMarginBuffer    float64  // 全仓保证金缓冲 =（全仓账户价值 - 全仓维持保证金）/ 全仓账户价值
+}
+
+// calcRisk 根据 clearinghouseState 计算每个持仓及交易员整体的风险指标，返回 coin -> positionRisk
+func calcRisk(ch *model.ClearinghouseState) (map[string]positionRisk, traderRisk) {
+	var tr traderRisk
+	positions := make(map[string]positionRisk, len(ch.AssetPositions))
+
+	totalNotional := 0.0
+	for _, ap := range ch.AssetPositions {
+		pv, _ := strconv.ParseFloat(ap.Position.PositionValue, 64)
+		totalNotional += math.Abs(pv)
+	}
+
+	leverageSum := 0.0
+	for _, ap := range ch.AssetPositions {
+		p := ap.Position
+		szi, _ := strconv.ParseFloat(p.Szi, 64)
+		pv, _ := strconv.ParseFloat(p.PositionValue, 64)
+		notional := math.Abs(pv)
+
+		var r positionRisk
+		if szi != 0 {
+			r.markPx = notional / math.Abs(szi)
+		}
+		if totalNotional > 0 {
+			r.concentration = notional / totalNotional
+		}
+		if p.LiquidationPx != nil && r.markPx > 0 {
+			if liq, err := strconv.ParseFloat(*p.LiquidationPx, 64); err == nil && liq > 0 {
+				// 多头价格下跌触发清算，空头价格上涨触发清算；已越过清算价时记为 0
+				dist := (r.markPx - liq) / r.markPx * 100
+				if szi < 0 {
+					dist = -dist
+				}
+				dist = math.Max(dist, 0)
+				r.liqDistancePct = &dist
+			}
+		}
+		positions[p.Coin] = r
+
+		leverageSum += notional * float64(p.Leverage.Value)
+		if r.concentration > tr.maxConcentration {
+			tr.maxConcentration = r.concentration
+			tr.maxConcentrationCoin = p.Coin
+		}
+		if r.liqDistancePct != nil && (tr.minLiqDistancePct == nil || *r.liqDistancePct < *tr.minLiqDistancePct) {
+			d := *r.liqDistancePct
+			tr.minLiqDistancePct = &d
+			tr.minLiqDistanceCoin = p.Coin
+		}
+	}
+	if totalNotional > 0 {
+		tr.weightedLeverage = leverageSum / totalNotional
+	}
+
+	crossValue, _ := strconv.ParseFloat(ch.CrossMarginSummary.AccountValue, 64)
+	crossMaint, _ := strconv.ParseFloat(ch.CrossMaintenanceMarginUsed, 64)
+	if crossValue > 0 {
+		tr.crossMarginBuffer = (crossValue - crossMaint) / crossValue
+	}
+
+	return positions, tr
+}
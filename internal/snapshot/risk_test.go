@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+func riskPosition(coin, szi, positionValue string, leverage int, liqPx *string) model.AssetPosition {
+	return model.AssetPosition{Position: model.Position{
+		Coin:          coin,
+		Szi:           szi,
+		PositionValue: positionValue,
+		Leverage:      model.Leverage{Type: "cross", Value: leverage},
+		LiquidationPx: liqPx,
+	}}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func floatPtrEqual(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return math.Abs(*a-*b) <= floatTolerance
+}
+
+func TestCalcRisk(t *testing.T) {
+	tests := []struct {
+		name      string
+		ch        model.ClearinghouseState
+		wantCoins map[string]positionRisk
+		want      traderRisk
+	}{
+		{
+			name: "no positions",
+			ch: model.ClearinghouseState{
+				CrossMarginSummary: model.MarginSummary{AccountValue: "0"},
+			},
+			wantCoins: map[string]positionRisk{},
+		},
+		{
+			name: "long and short",
+			ch: model.ClearinghouseState{
+				AssetPositions: []model.AssetPosition{
+					riskPosition("BTC", "2", "100000", 10, ptr("40000")),
+					riskPosition("ETH", "-10", "30000", 5, ptr("3300")),
+				},
+				CrossMarginSummary:         model.MarginSummary{AccountValue: "1000"},
+				CrossMaintenanceMarginUsed: "250",
+			},
+			wantCoins: map[string]positionRisk{
+				"BTC": {markPx: 50000, liqDistancePct: ptr(20.0), concentration: 100000.0 / 130000},
+				"ETH": {markPx: 3000, liqDistancePct: ptr(10.0), concentration: 30000.0 / 130000},
+			},
+			want: traderRisk{
+				weightedLeverage:     (100000.0*10 + 30000*5) / 130000,
+				maxConcentration:     100000.0 / 130000,
+				maxConcentrationCoin: "BTC",
+				minLiqDistancePct:    ptr(10.0),
+				minLiqDistanceCoin:   "ETH",
+				crossMarginBuffer:    0.75,
+			},
+		},
+		{
+			name: "past liquidation and missing liquidation price",
+			ch: model.ClearinghouseState{
+				AssetPositions: []model.AssetPosition{
+					riskPosition("SOL", "1", "100", 20, ptr("120")),
+					riskPosition("DOGE", "-100", "100", 3, nil),
+				},
+			},
+			wantCoins: map[string]positionRisk{
+				"SOL":  {markPx: 100, liqDistancePct: ptr(0.0), concentration: 0.5},
+				"DOGE": {markPx: 1, concentration: 0.5},
+			},
+			want: traderRisk{
+				weightedLeverage:     11.5,
+				maxConcentration:     0.5,
+				maxConcentrationCoin: "SOL",
+				minLiqDistancePct:    ptr(0.0),
+				minLiqDistanceCoin:   "SOL",
+			},
+		},
+		{
+			name: "zero size",
+			ch: model.ClearinghouseState{
+				AssetPositions: []model.AssetPosition{riskPosition("BTC", "0", "0", 10, ptr("40000"))},
+			},
+			wantCoins: map[string]positionRisk{"BTC": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coins, tr := calcRisk(&tt.ch)
+			if len(coins) != len(tt.wantCoins) {
+				t.Fatalf("calcRisk() returned %d coins, want %d", len(coins), len(tt.wantCoins))
+			}
+			for coin, want := range tt.wantCoins {
+				got := coins[coin]
+				if math.Abs(got.markPx-want.markPx) > floatTolerance ||
+					math.Abs(got.concentration-want.concentration) > floatTolerance ||
+					!floatPtrEqual(got.liqDistancePct, want.liqDistancePct) {
+					t.Errorf("%s risk = %+v, want %+v", coin, got, want)
+				}
+			}
+			if math.Abs(tr.weightedLeverage-tt.want.weightedLeverage) > floatTolerance ||
+				math.Abs(tr.maxConcentration-tt.want.maxConcentration) > floatTolerance ||
+				tr.maxConcentrationCoin != tt.want.maxConcentrationCoin ||
+				!floatPtrEqual(tr.minLiqDistancePct, tt.want.minLiqDistancePct) ||
+				tr.minLiqDistanceCoin != tt.want.minLiqDistanceCoin ||
+				math.Abs(tr.crossMarginBuffer-tt.want.crossMarginBuffer) > floatTolerance {
+				t.Errorf("trader risk = %+v, want %+v", tr, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hypercopy/crawler/internal/consts"
+	"github.com/hypercopy/crawler/internal/model"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type cond = model.LabelRuleCondition
+
+// DefaultLabelRules 内置默认规则（version 0），数据库中没有 active 版本时使用
+func DefaultLabelRules() model.LabelRules {
+	// 盈利状态的公共门槛：90天活跃≥30 且 笔数≥30 且 总收益率>5%
+	profitGate := []cond{
+		{Metric: "active_days_90", Operator: ">=", Threshold: 30},
+		{Metric: "trade_count_90", Operator: ">=", Threshold: 30},
+		{Metric: "total_roi_pct", Operator: ">", Threshold: 5},
+	}
+	withGate := func(extra ...cond) []cond {
+		return append(append([]cond{}, profitGate...), extra...)
+	}
+
+	return model.LabelRules{
+		// 账户总价值
+		{Category: consts.LabelCategoryAccountSize, Label: consts.AccountTotalValueWhale, Priority: 1,
+			Conditions: []cond{{Metric: "total_value", Operator: ">", Threshold: 500_000}}},
+		{Category: consts.LabelCategoryAccountSize, Label: consts.AccountTotalValueMedium, Priority: 2,
+			Conditions: []cond{{Metric: "total_value", Operator: ">=", Threshold: 100_000}}},
+		{Category: consts.LabelCategoryAccountSize, Label: consts.AccountTotalValueSmall, Priority: 3,
+			Conditions: []cond{{Metric: "total_value", Operator: ">", Threshold: 0}}},
+
+		// 交易节奏
+		{Category: consts.LabelCategoryTradingRhythm, Label: consts.TradingRhythmLongTerm, Priority: 1,
+			Conditions: []cond{{Metric: "avg_holding_ms", Operator: ">", Threshold: msPerWeek}}},
+		{Category: consts.LabelCategoryTradingRhythm, Label: consts.TradingRhythmSwing, Priority: 2,
+			Conditions: []cond{{Metric: "avg_holding_ms", Operator: ">", Threshold: msPerDay}}},
+		{Category: consts.LabelCategoryTradingRhythm, Label: consts.TradingRhythmShortTerm, Priority: 3,
+			Conditions: []cond{{Metric: "avg_holding_ms", Operator: ">=", Threshold: msPerHour}}},
+		{Category: consts.LabelCategoryTradingRhythm, Label: consts.TradingRhythmScalping, Priority: 4},
+
+		// 盈利状态
+		{Category: consts.LabelCategoryProfitStatus, Label: consts.ProfitStatusBreakeven, Priority: 1,
+			Conditions: []cond{
+				{Metric: "total_roi_pct", Operator: ">=", Threshold: -5},
+				{Metric: "total_roi_pct", Operator: "<=", Threshold: 5},
+			}},
+		{Category: consts.LabelCategoryProfitStatus, Label: consts.ProfitStatusConsistent, Priority: 2,
+			Conditions: withGate(
+				cond{Metric: "profit_factor", Operator: ">=", Threshold: 1.3},
+				cond{Metric: "expectancy", Operator: ">", Threshold: 0},
+				cond{Metric: "rolling_30d_return", Operator: ">", Threshold: 0},
+			)},
+		{Category: consts.LabelCategoryProfitStatus, Label: consts.ProfitStatusVolatile, Priority: 3,
+			Conditions: withGate(cond{Metric: "profit_factor", Operator: "<", Threshold: 1.3})},
+		{Category: consts.LabelCategoryProfitStatus, Label: consts.ProfitStatusVolatile, Priority: 4,
+			Conditions: withGate(cond{Metric: "rolling_30d_return", Operator: "<=", Threshold: 0})},
+
+		// 方向偏好
+		{Category: consts.LabelCategoryDirection, Label: consts.DirectionPreferenceBearish, Priority: 1,
+			Conditions: []cond{{Metric: "long_pct", Operator: "<", Threshold: 30}}},
+		{Category: consts.LabelCategoryDirection, Label: consts.DirectionPreferenceBullish, Priority: 2,
+			Conditions: []cond{{Metric: "long_pct", Operator: ">", Threshold: 70}}},
+		{Category: consts.LabelCategoryDirection, Label: consts.DirectionPreferenceNeutral, Priority: 3},
+
+		// 交易风格（可多选）
+		{Category: consts.LabelCategoryTradingStyle, Label: consts.TradingStyleHFSteady, Multi: true,
+			Conditions: []cond{
+				{Metric: "avg_holding_days", Operator: "<=", Threshold: 1},
+				{Metric: "win_rate", Operator: ">", Threshold: 0.6},
+				{Metric: "risk_reward_ratio", Operator: ">=", Threshold: 1.2},
+				{Metric: "profit_factor", Operator: ">=", Threshold: 1.2},
+				{Metric: "trade_count_30", Operator: ">=", Threshold: 20},
+			}},
+		{Category: consts.LabelCategoryTradingStyle, Label: consts.TradingStyleHFAggressive, Multi: true,
+			Conditions: []cond{
+				{Metric: "avg_holding_days", Operator: "<=", Threshold: 1},
+				{Metric: "win_rate", Operator: "<", Threshold: 0.5},
+				{Metric: "risk_reward_ratio", Operator: ">=", Threshold: 5},
+				{Metric: "profit_factor", Operator: ">=", Threshold: 1.5},
+				{Metric: "trade_count_30", Operator: ">=", Threshold: 20},
+			}},
+		{Category: consts.LabelCategoryTradingStyle, Label: consts.TradingStyleLFSteady, Multi: true,
+			Conditions: []cond{
+				{Metric: "avg_holding_days", Operator: ">", Threshold: 7},
+				{Metric: "win_rate", Operator: ">", Threshold: 0.6},
+				{Metric: "risk_reward_ratio", Operator: ">=", Threshold: 1.2},
+				{Metric: "profit_factor", Operator: ">=", Threshold: 1.2},
+				{Metric: "trade_count_30", Operator: "<=", Threshold: 10},
+			}},
+		{Category: consts.LabelCategoryTradingStyle, Label: consts.TradingStyleStableProfit, Multi: true,
+			Conditions: []cond{
+				{Metric: "win_rate", Operator: ">=", Threshold: 0.6},
+				{Metric: "risk_reward_ratio", Operator: ">=", Threshold: 1.5},
+				{Metric: "max_drawdown", Operator: "<=", Threshold: 0.25},
+				{Metric: "profit_factor", Operator: ">=", Threshold: 1.5},
+				{Metric: "sharpe", Operator: ">=", Threshold: 1.0},
+			}},
+		{Category: consts.LabelCategoryTradingStyle, Label: consts.TradingStyleHighRiskReward, Multi: true,
+			Conditions: []cond{
+				{Metric: "risk_reward_ratio", Operator: ">=", Threshold: 3},
+				{Metric: "avg_profit_per_win", Operator: ">=", Threshold: 10_000},
+				{Metric: "max_drawdown", Operator: ">=", Threshold: 0.3},
+			}},
+		{Category: consts.LabelCategoryTradingStyle, Label: consts.TradingStyleAsymmetric, Multi: true,
+			Conditions: []cond{
+				{Metric: "win_rate", Operator: "<", Threshold: 0.5},
+				{Metric: "risk_reward_ratio", Operator: ">=", Threshold: 5},
+				{Metric: "profit_factor", Operator: ">=", Threshold: 1.5},
+				{Metric: "trade_count_30", Operator: "<=", Threshold: 20},
+			}},
+		{Category: consts.LabelCategoryTradingStyle, Label: consts.TradingStyleLowDrawdown, Multi: true,
+			Conditions: []cond{
+				{Metric: "has_series_90", Operator: "=", Threshold: 1},
+				{Metric: "max_drawdown_90", Operator: "<=", Threshold: 0.2},
+			}},
+		{Category: consts.LabelCategoryTradingStyle, Label: consts.TradingStyleVolatility, Multi: true,
+			Conditions: []cond{
+				{Metric: "sharpe", Operator: "<", Threshold: 0.8},
+				{Metric: "profit_factor", Operator: ">=", Threshold: 1.0},
+			}},
+
+		// 盈利规模
+		{Category: consts.LabelCategoryProfitScale, Label: consts.ProfitScaleLarge, Priority: 1,
+			Conditions: []cond{{Metric: "avg_profit_per_win", Operator: ">", Threshold: 50_000}}},
+		{Category: consts.LabelCategoryProfitScale, Label: consts.ProfitScaleMedium, Priority: 2,
+			Conditions: []cond{{Metric: "avg_profit_per_win", Operator: ">=", Threshold: 3_000}}},
+		{Category: consts.LabelCategoryProfitScale, Label: consts.ProfitScaleSmall, Priority: 3,
+			Conditions: []cond{{Metric: "avg_profit_per_win", Operator: ">", Threshold: 0}}},
+	}
+}
+
+// ValidateLabelRules 检查规则引用的指标与运算符是否合法
+func ValidateLabelRules(rules model.LabelRules) error {
+	var probe labelMetrics
+	for i, r := range rules {
+		if r.Category == "" || r.Label == "" {
+			return fmt.Errorf("rule %d: category and label are required", i)
+		}
+		for _, c := range r.Conditions {
+			if _, ok := probe.metric(c.Metric); !ok {
+				return fmt.Errorf("rule %d (%s): unknown metric %q", i, r.Label, c.Metric)
+			}
+			if _, ok := compareOps[c.Operator]; !ok {
+				return fmt.Errorf("rule %d (%s): unknown operator %q", i, r.Label, c.Operator)
+			}
+		}
+	}
+	return nil
+}
+
+var compareOps = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"=":  func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+func ruleMatches(r model.LabelRule, m labelMetrics) bool {
+	if m.tradeCount < r.MinTradeCount {
+		return false
+	}
+	for _, c := range r.Conditions {
+		v, ok := m.metric(c.Metric)
+		if !ok {
+			return false
+		}
+		op, ok := compareOps[c.Operator]
+		if !ok || !op(v, c.Threshold) {
+			return false
+		}
+	}
+	return true
+}
+
+// evalLabelRules 按类别出现顺序输出标签；非 Multi 类别只取优先级最高的命中规则
+func evalLabelRules(rules model.LabelRules, m labelMetrics) []string {
+	hits := matchLabelRules(rules, m)
+	if len(hits) == 0 {
+		return nil
+	}
+	labels := make([]string, len(hits))
+	for i, h := range hits {
+		labels[i] = h.label
+	}
+	return labels
+}
+
+type labelHit struct {
+	category string
+	label    string
+}
+
+func matchLabelRules(rules model.LabelRules, m labelMetrics) []labelHit {
+	var categories []string
+	byCategory := make(map[string][]model.LabelRule)
+	for _, r := range rules {
+		if _, ok := byCategory[r.Category]; !ok {
+			categories = append(categories, r.Category)
+		}
+		byCategory[r.Category] = append(byCategory[r.Category], r)
+	}
+
+	var hits []labelHit
+	for _, category := range categories {
+		group := byCategory[category]
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Priority < group[j].Priority })
+
+		seen := make(map[string]struct{})
+		for _, r := range group {
+			if !ruleMatches(r, m) {
+				continue
+			}
+			if _, dup := seen[r.Label]; !dup {
+				seen[r.Label] = struct{}{}
+				hits = append(hits, labelHit{category: category, label: r.Label})
+			}
+			if !r.Multi {
+				break
+			}
+		}
+	}
+	return hits
+}
+
+// categoryLabel 返回指定类别命中的第一个标签（如 account_size），未命中时为空。
+// 与 computeLabels 不同，没有已完成交易的交易员也会参与匹配。
+func categoryLabel(rules model.LabelRules, m labelMetrics, category string) string {
+	for _, h := range matchLabelRules(rules, m) {
+		if h.category == category {
+			return h.label
+		}
+	}
+	return ""
+}
+
+// ── rule set loading ────────────────────────────────────────────────
+
+// loadActiveLabelRuleSet 读取 active 版本；没有时返回内置默认规则（version 0）
+func loadActiveLabelRuleSet(db *gorm.DB) (*model.LabelRuleSet, error) {
+	var set model.LabelRuleSet
+	err := db.Where("status = ?", model.LabelRuleSetStatusActive).Order("version DESC").First(&set).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.LabelRuleSet{Version: 0, Status: model.LabelRuleSetStatusActive, Rules: DefaultLabelRules()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// reloadLabelRules 每轮开始时热加载 active 规则；加载失败或规则非法时沿用当前规则
+func (s *Syncer) reloadLabelRules() {
+	set, err := loadActiveLabelRuleSet(s.db)
+	if err != nil {
+		zap.S().Errorf("[snapshot] load label rules error: %v, keeping current rules", err)
+		return
+	}
+	if err := ValidateLabelRules(set.Rules); err != nil {
+		zap.S().Errorf("[snapshot] label rules version %d invalid: %v, keeping current rules", set.Version, err)
+		return
+	}
+
+	cur := s.labelRules.Load()
+	if cur != nil && cur.Version == set.Version && cur.UpdatedAt.Equal(set.UpdatedAt) {
+		return
+	}
+	s.labelRules.Store(set)
+	zap.S().Infof("[snapshot] label rules loaded: version %d (%d rules)", set.Version, len(set.Rules))
+}
+
+func (s *Syncer) currentLabelRules() model.LabelRules {
+	if set := s.labelRules.Load(); set != nil {
+		return set.Rules
+	}
+	return DefaultLabelRules()
+}
@@ -0,0 +1,124 @@
+package snapshot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+func TestMatchLabelRules(t *testing.T) {
+	rules := model.LabelRules{
+		{Category: "size", Label: "whale", Priority: 1, Conditions: []cond{{Metric: "total_value", Operator: ">", Threshold: 500}}},
+		{Category: "size", Label: "small", Priority: 2},
+		{Category: "style", Label: "winner", Multi: true, Conditions: []cond{{Metric: "win_rate", Operator: ">=", Threshold: 0.6}}},
+		{Category: "style", Label: "active", Multi: true, MinTradeCount: 10},
+		{Category: "style", Label: "winner", Multi: true, Conditions: []cond{{Metric: "sharpe", Operator: ">", Threshold: 1}}},
+	}
+	tests := []struct {
+		name string
+		m    labelMetrics
+		want []labelHit
+	}{
+		{
+			name: "priority picks first match",
+			m:    labelMetrics{totalValue: 1000},
+			want: []labelHit{{"size", "whale"}},
+		},
+		{
+			name: "fallback rule without conditions",
+			m:    labelMetrics{totalValue: 100},
+			want: []labelHit{{"size", "small"}},
+		},
+		{
+			name: "multi category keeps all hits and dedupes labels",
+			m:    labelMetrics{totalValue: 100, winRate: 0.7, sharpe: 2, tradeCount: 20},
+			want: []labelHit{{"size", "small"}, {"style", "winner"}, {"style", "active"}},
+		},
+		{
+			name: "min trade count",
+			m:    labelMetrics{totalValue: 100, tradeCount: 9},
+			want: []labelHit{{"size", "small"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchLabelRules(rules, tt.m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchLabelRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryLabel(t *testing.T) {
+	rules := DefaultLabelRules()
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, ""},
+		{50_000, "small"},
+		{100_000, "medium"},
+		{500_000, "medium"},
+		{500_001, "whale"},
+	}
+	for _, tt := range tests {
+		if got := categoryLabel(rules, labelMetrics{totalValue: tt.value}, "account_size"); got != tt.want {
+			t.Errorf("categoryLabel(total_value=%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidateLabelRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   model.LabelRules
+		wantErr string
+	}{
+		{name: "default rules", rules: DefaultLabelRules()},
+		{name: "empty", rules: nil},
+		{
+			name:    "missing label",
+			rules:   model.LabelRules{{Category: "size"}},
+			wantErr: "category and label are required",
+		},
+		{
+			name:    "unknown metric",
+			rules:   model.LabelRules{{Category: "size", Label: "x", Conditions: []cond{{Metric: "nope", Operator: ">"}}}},
+			wantErr: `unknown metric "nope"`,
+		},
+		{
+			name:    "unknown operator",
+			rules:   model.LabelRules{{Category: "size", Label: "x", Conditions: []cond{{Metric: "sharpe", Operator: "=>"}}}},
+			wantErr: `unknown operator "=>"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLabelRules(tt.rules)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateLabelRules() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateLabelRules() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLabelMetricsSnapshotRoundTrip(t *testing.T) {
+	m := labelMetrics{
+		totalValue: 1, avgHoldingMs: 2, activeDays90: 3, tradeCount: 4, tradeCount90: 5, tradeCount30: 6,
+		totalROI: 0.07, profitFactor: 8, expectedValue: 9, rolling30Return: 10, longRatio: 0.11,
+		winRate: 12, riskRewardRatio: 13, maxDrawdown: 14, maxDrawdown90: 15, sharpe: 16,
+		avgProfitPerWin: 17, hasSeries90: true,
+	}
+	got := labelMetricsFromSnapshot(m.snapshot())
+	if !reflect.DeepEqual(got.snapshot(), m.snapshot()) {
+		t.Errorf("round trip = %+v, want %+v", got, m)
+	}
+}
@@ -0,0 +1,309 @@
+package snapshot
+
+import (
+	"container/heap"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"go.uber.org/zap"
+)
+
+// 刷新间隔上下限：跟单中的活跃交易员秒级刷新，无持仓、无成交的沉寂账户每小时刷新一次
+const (
+	minRefreshInterval    = 10 * time.Second
+	maxRefreshInterval    = time.Hour
+	errorRetryInterval    = 5 * time.Minute
+	recentFillWindow      = time.Hour
+	highLeverageThreshold = 10
+)
+
+// traderSignals 决定刷新优先级的信号
+type traderSignals struct {
+	positions   int     // 当前持仓数
+	leverage    float64 // 有效杠杆
+	recentFills int     // recentFillWindow 内的成交笔数
+	trackers    int     // 跟踪该地址并开启通知的用户数
+	copiers     int     // 跟单该地址的启用配置数
+}
+
+// refreshInterval 按信号计算下一次刷新间隔，取各信号对应间隔的最小值
+func refreshInterval(sig traderSignals) time.Duration {
+	interval := maxRefreshInterval
+	if sig.positions > 0 {
+		interval = 5 * time.Minute
+		if sig.positions >= 5 {
+			interval = 2 * time.Minute
+		}
+		// 高杠杆仓位离强平更近，状态变化更快
+		if sig.leverage >= highLeverageThreshold {
+			interval /= 2
+		}
+	}
+	if sig.recentFills > 0 {
+		interval = min(interval, time.Minute)
+	}
+	if sig.trackers > 0 {
+		interval = min(interval, time.Minute)
+	}
+	if sig.copiers > 0 {
+		interval = min(interval, 15*time.Second)
+	}
+	return max(interval, minRefreshInterval)
+}
+
+type scheduleItem struct {
+	address string
+	due     time.Time
+	index   int
+}
+
+// scheduleQueue 按到期时间排序的小顶堆
+type scheduleQueue []*scheduleItem
+
+func (q scheduleQueue) Len() int           { return len(q) }
+func (q scheduleQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduleQueue) Push(x any) {
+	item := x.(*scheduleItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *scheduleQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*q = old[:n-1]
+	return item
+}
+
+// scheduler 内存优先队列，worker 从中取出到期的交易员刷新，刷新后按新的间隔放回
+type scheduler struct {
+	mu      sync.Mutex
+	queue   scheduleQueue
+	queued  map[string]*scheduleItem // 在队列中等待的地址
+	tracked map[string]struct{}      // 调度中的全部地址（含正在刷新的）
+	wake    chan struct{}
+
+	// 周期性批量加载的信号，地址统一小写
+	recentFills map[string]int
+	trackers    map[string]int
+	copiers     map[string]int
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		queued:  make(map[string]*scheduleItem),
+		tracked: make(map[string]struct{}),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (sc *scheduler) notify() {
+	select {
+	case sc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sync 与 traders 表对齐：新地址立即到期，已删除的地址移出调度，返回新增与移除数量
+func (sc *scheduler) sync(addresses []string) (added, removed int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	now := time.Now()
+	current := make(map[string]struct{}, len(addresses))
+	for _, addr := range addresses {
+		current[addr] = struct{}{}
+		if _, ok := sc.tracked[addr]; ok {
+			continue
+		}
+		sc.tracked[addr] = struct{}{}
+		sc.pushLocked(addr, now)
+		added++
+	}
+	for addr := range sc.tracked {
+		if _, ok := current[addr]; ok {
+			continue
+		}
+		delete(sc.tracked, addr)
+		if item, ok := sc.queued[addr]; ok {
+			heap.Remove(&sc.queue, item.index)
+			delete(sc.queued, addr)
+		}
+		removed++
+	}
+	if added > 0 {
+		sc.notify()
+	}
+	return added, removed
+}
+
+func (sc *scheduler) pushLocked(addr string, due time.Time) {
+	item := &scheduleItem{address: addr, due: due}
+	heap.Push(&sc.queue, item)
+	sc.queued[addr] = item
+}
+
+// next 阻塞直到有交易员到期，取出并返回其地址
+func (sc *scheduler) next() string {
+	for {
+		sc.mu.Lock()
+		if sc.queue.Len() == 0 {
+			sc.mu.Unlock()
+			<-sc.wake
+			continue
+		}
+		top := sc.queue[0]
+		wait := time.Until(top.due)
+		if wait <= 0 {
+			heap.Pop(&sc.queue)
+			delete(sc.queued, top.address)
+			// 还有到期项时继续唤醒其他空闲 worker
+			if sc.queue.Len() > 0 && !sc.queue[0].due.After(time.Now()) {
+				sc.notify()
+			}
+			sc.mu.Unlock()
+			return top.address
+		}
+		sc.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-sc.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// reschedule 刷新完成后放回队列；刷新期间已被移出调度的地址不再放回
+func (sc *scheduler) reschedule(addr string, interval time.Duration) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, ok := sc.tracked[addr]; !ok {
+		return
+	}
+	if _, ok := sc.queued[addr]; ok {
+		return
+	}
+	sc.pushLocked(addr, time.Now().Add(interval))
+	sc.notify()
+}
+
+// pullInLocked 将已在队列中的地址提前到 due（只提前不推后）
+func (sc *scheduler) pullInLocked(addr string, due time.Time) {
+	item, ok := sc.queued[addr]
+	if !ok || !due.Before(item.due) {
+		return
+	}
+	item.due = due
+	heap.Fix(&sc.queue, item.index)
+}
+
+// setSignals 更新批量信号；新出现跟单/跟踪/成交的地址按新间隔提前刷新
+func (sc *scheduler) setSignals(recentFills, trackers, copiers map[string]int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.recentFills, sc.trackers, sc.copiers = recentFills, trackers, copiers
+
+	now := time.Now()
+	for _, m := range []map[string]int{recentFills, trackers, copiers} {
+		for addr := range m {
+			sig := sc.signalsLocked(addr)
+			sc.pullInLocked(addr, now.Add(refreshInterval(sig)))
+		}
+	}
+	sc.notify()
+}
+
+// signals 返回地址的批量信号（持仓数、杠杆由调用方补充）
+func (sc *scheduler) signals(addr string) traderSignals {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.signalsLocked(addr)
+}
+
+func (sc *scheduler) signalsLocked(addr string) traderSignals {
+	key := strings.ToLower(addr)
+	return traderSignals{
+		recentFills: sc.recentFills[key],
+		trackers:    sc.trackers[key],
+		copiers:     sc.copiers[key],
+	}
+}
+
+func (sc *scheduler) size() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return len(sc.tracked)
+}
+
+// nextInterval 结合刚写入的持仓快照与批量信号计算下一次刷新间隔
+func (s *Syncer) nextInterval(address string) time.Duration {
+	sig := s.sched.signals(address)
+
+	var trader model.Trader
+	if err := s.db.Select("snap_position_count", "snap_eff_leverage").
+		Where("address = ?", address).Limit(1).Find(&trader).Error; err != nil {
+		zap.S().Warnf("[snapshot] load schedule snap %s: %v", utility.Abbr(address), err)
+	}
+	sig.positions = trader.SnapPositionCount
+	sig.leverage, _ = strconv.ParseFloat(trader.SnapEffLeverage, 64)
+
+	return refreshInterval(sig)
+}
+
+// loadScheduleSignals 批量加载近期成交笔数、跟踪人数、跟单配置数，地址统一小写
+func (s *Syncer) loadScheduleSignals() (recentFills, trackers, copiers map[string]int, err error) {
+	count := func(query string, args ...interface{}) (map[string]int, error) {
+		var rows []struct {
+			Address string
+			Cnt     int
+		}
+		if err := s.db.Raw(query, args...).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		out := make(map[string]int, len(rows))
+		for _, r := range rows {
+			out[r.Address] = r.Cnt
+		}
+		return out, nil
+	}
+
+	recentFills, err = count(`
+		SELECT lower(address) AS address, COUNT(*) AS cnt
+		FROM trader_fills WHERE time >= ?
+		GROUP BY lower(address)`,
+		time.Now().Add(-recentFillWindow).UnixMilli())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("recent fills: %w", err)
+	}
+	trackers, err = count(`
+		SELECT lower(wallet) AS address, COUNT(*) AS cnt
+		FROM my_track_wallet WHERE status = 1 AND enable_notify = 1
+		GROUP BY lower(wallet)`)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("trackers: %w", err)
+	}
+	copiers, err = count(`
+		SELECT lower(target_wallet) AS address, COUNT(*) AS cnt
+		FROM copy_trade_config WHERE status = 1
+		GROUP BY lower(target_wallet)`)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("copiers: %w", err)
+	}
+	return recentFills, trackers, copiers, nil
+}
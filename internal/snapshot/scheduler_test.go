@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRefreshInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		sig  traderSignals
+		want time.Duration
+	}{
+		{"idle", traderSignals{}, maxRefreshInterval},
+		{"few positions", traderSignals{positions: 2}, 5 * time.Minute},
+		{"many positions", traderSignals{positions: 5}, 2 * time.Minute},
+		{"high leverage", traderSignals{positions: 2, leverage: highLeverageThreshold}, 150 * time.Second},
+		{"high leverage many positions", traderSignals{positions: 6, leverage: 20}, time.Minute},
+		{"leverage without positions", traderSignals{leverage: 50}, maxRefreshInterval},
+		{"recent fills", traderSignals{recentFills: 3}, time.Minute},
+		{"trackers", traderSignals{positions: 1, trackers: 1}, time.Minute},
+		{"copiers", traderSignals{copiers: 1}, 15 * time.Second},
+		{"floor", traderSignals{positions: 10, leverage: 50, copiers: 3}, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refreshInterval(tt.sig); got != tt.want {
+				t.Errorf("refreshInterval(%+v) = %v, want %v", tt.sig, got, tt.want)
+			}
+			if got := refreshInterval(tt.sig); got < minRefreshInterval || got > maxRefreshInterval {
+				t.Errorf("refreshInterval(%+v) = %v out of [%v, %v]", tt.sig, got, minRefreshInterval, maxRefreshInterval)
+			}
+		})
+	}
+}
+
+func TestSchedulerOrdering(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		due    map[string]time.Duration // 相对 now 的到期时间
+		pullIn map[string]time.Duration // pullInLocked 的目标到期时间
+		want   []string
+	}{
+		{
+			name: "by due",
+			due:  map[string]time.Duration{"a": 3 * time.Second, "b": time.Second, "c": 2 * time.Second},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name:   "pull in",
+			due:    map[string]time.Duration{"a": 3 * time.Second, "b": time.Second, "c": 2 * time.Second},
+			pullIn: map[string]time.Duration{"a": 0},
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name:   "pull in never pushes back",
+			due:    map[string]time.Duration{"a": time.Second, "b": 2 * time.Second},
+			pullIn: map[string]time.Duration{"a": time.Hour},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "pull in unknown address",
+			due:    map[string]time.Duration{"a": time.Second},
+			pullIn: map[string]time.Duration{"z": 0},
+			want:   []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newScheduler()
+			sc.mu.Lock()
+			for addr, d := range tt.due {
+				sc.tracked[addr] = struct{}{}
+				sc.pushLocked(addr, now.Add(d-time.Hour))
+			}
+			for addr, d := range tt.pullIn {
+				sc.pullInLocked(addr, now.Add(d-time.Hour))
+			}
+			sc.mu.Unlock()
+
+			for i, want := range tt.want {
+				if got := sc.next(); got != want {
+					t.Fatalf("next() #%d = %q, want %q", i, got, want)
+				}
+			}
+			if sc.queue.Len() != 0 || len(sc.queued) != 0 {
+				t.Errorf("queue not drained: len=%d queued=%d", sc.queue.Len(), len(sc.queued))
+			}
+		})
+	}
+}
+
+func TestSchedulerSync(t *testing.T) {
+	sc := newScheduler()
+	if added, removed := sc.sync([]string{"a", "b"}); added != 2 || removed != 0 {
+		t.Fatalf("sync = (%d, %d), want (2, 0)", added, removed)
+	}
+	if added, removed := sc.sync([]string{"b", "c"}); added != 1 || removed != 1 {
+		t.Fatalf("sync = (%d, %d), want (1, 1)", added, removed)
+	}
+	if _, ok := sc.queued["a"]; ok {
+		t.Error("removed address still queued")
+	}
+
+	// 已移出调度的地址刷新完成后不再放回
+	sc.next()
+	sc.next()
+	sc.sync([]string{"c"})
+	sc.reschedule("b", time.Minute)
+	if _, ok := sc.queued["b"]; ok {
+		t.Error("untracked address rescheduled")
+	}
+	sc.reschedule("c", time.Minute)
+	if _, ok := sc.queued["c"]; !ok {
+		t.Error("tracked address not rescheduled")
+	}
+}
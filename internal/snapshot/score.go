@@ -0,0 +1,268 @@
+package snapshot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 综合评分分项及默认权重（system_setting.score_weights 可覆盖）
+var scoreComponents = []string{
+	"risk_adjusted", "drawdown", "consistency", "sample_size", "recency", "fee_drag", "liquidation",
+}
+
+var defaultScoreWeights = model.ScoreWeights{
+	"risk_adjusted": 0.25,
+	"drawdown":      0.15,
+	"consistency":   0.15,
+	"sample_size":   0.10,
+	"recency":       0.10,
+	"fee_drag":      0.10,
+	"liquidation":   0.15,
+}
+
+// 盈亏比无亏损时记为 999，评分时截断，避免少量全胜交易员霸榜
+const maxScoreProfitFactor = 10
+
+type scoreInput struct {
+	address string
+	raw     map[string]float64 // 各分项原始值，均已转换为“越大越好”
+}
+
+// updateScores 在每轮快照结束后重算所有窗口的综合评分、排名与百分位
+func (s *Syncer) updateScores() error {
+	weights := s.loadScoreWeights()
+
+	var stats []model.TraderStatistic
+	if err := s.db.Select("address", "window", "sortino", "drawdown", "profit_factor",
+		"long_count", "short_count", "fee_pnl_ratio", "net_fee").Find(&stats).Error; err != nil {
+		return fmt.Errorf("load statistics: %w", err)
+	}
+
+	lastTrade, err := s.loadLastTradeTimes()
+	if err != nil {
+		return fmt.Errorf("load last trade times: %w", err)
+	}
+	liquidations, err := s.loadLiquidationCounts()
+	if err != nil {
+		return fmt.Errorf("load liquidation counts: %w", err)
+	}
+
+	nowMs := float64(time.Now().UnixMilli())
+	byWindow := make(map[string][]scoreInput)
+	for _, st := range stats {
+		trades := parseNum(st.LongCount) + parseNum(st.ShortCount)
+		if trades == 0 {
+			continue
+		}
+
+		feeDrag := parseNum(st.FeePnlRatio)
+		if feeDrag == 0 && parseNum(st.NetFee) > 0 {
+			// 毛盈亏不为正但仍在付手续费，按手续费吃掉全部盈利处理
+			feeDrag = 1
+		}
+		recencyDays := 3650.0
+		if ms, ok := lastTrade[st.Address]; ok {
+			recencyDays = (nowMs - float64(ms)) / msPerDay
+		}
+
+		byWindow[st.Window] = append(byWindow[st.Window], scoreInput{
+			address: st.Address,
+			raw: map[string]float64{
+				"risk_adjusted": parseNum(st.Sortino),
+				"drawdown":      -parseNum(st.Drawdown),
+				"consistency":   math.Min(parseNum(st.ProfitFactor), maxScoreProfitFactor),
+				"sample_size":   trades,
+				"recency":       -recencyDays,
+				"fee_drag":      -feeDrag,
+				"liquidation":   -float64(liquidations[st.Window][st.Address]),
+			},
+		})
+	}
+
+	for _, window := range allWindows {
+		records := buildScores(window, byWindow[window], weights)
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("\"window\" = ?", window).Delete(&model.TraderScore{}).Error; err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				return nil
+			}
+			return tx.CreateInBatches(records, 500).Error
+		})
+		if err != nil {
+			return fmt.Errorf("save %s scores: %w", window, err)
+		}
+		zap.S().Infof("[snapshot] scores %s: %d traders ranked", window, len(records))
+	}
+	return nil
+}
+
+func buildScores(window string, inputs []scoreInput, weights model.ScoreWeights) []model.TraderScore {
+	n := len(inputs)
+	if n == 0 {
+		return nil
+	}
+
+	pct := make(map[string][]float64, len(scoreComponents))
+	for _, c := range scoreComponents {
+		values := make([]float64, n)
+		for i, in := range inputs {
+			values[i] = in.raw[c]
+		}
+		pct[c] = percentileRanks(values)
+	}
+
+	totalWeight := 0.0
+	for _, c := range scoreComponents {
+		totalWeight += weights[c]
+	}
+	scores := make([]float64, n)
+	for i := range inputs {
+		sum := 0.0
+		for _, c := range scoreComponents {
+			sum += weights[c] * pct[c][i]
+		}
+		if totalWeight > 0 {
+			scores[i] = sum / totalWeight * 100
+		}
+	}
+	scorePct := percentileRanks(scores)
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	records := make([]model.TraderScore, n)
+	for rank, i := range order {
+		records[rank] = model.TraderScore{
+			Address:          inputs[i].address,
+			Window:           window,
+			Score:            utility.FmtFloat(scores[i]),
+			Rank:             rank + 1,
+			Percentile:       utility.FmtFloat(scorePct[i]),
+			RiskAdjusted:     utility.FmtFloat(pct["risk_adjusted"][i]),
+			DrawdownScore:    utility.FmtFloat(pct["drawdown"][i]),
+			Consistency:      utility.FmtFloat(pct["consistency"][i]),
+			SampleSize:       utility.FmtFloat(pct["sample_size"][i]),
+			Recency:          utility.FmtFloat(pct["recency"][i]),
+			FeeDrag:          utility.FmtFloat(pct["fee_drag"][i]),
+			LiquidationScore: utility.FmtFloat(pct["liquidation"][i]),
+		}
+	}
+	return records
+}
+
+// percentileRanks 返回每个值在样本中的百分位（0~1），相同值取平均名次
+func percentileRanks(values []float64) []float64 {
+	n := len(values)
+	out := make([]float64, n)
+	if n == 1 {
+		out[0] = 1
+		return out
+	}
+
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return values[idx[a]] < values[idx[b]] })
+
+	for i := 0; i < n; {
+		j := i
+		for j+1 < n && values[idx[j+1]] == values[idx[i]] {
+			j++
+		}
+		p := float64(i+j) / 2 / float64(n-1)
+		for k := i; k <= j; k++ {
+			out[idx[k]] = p
+		}
+		i = j + 1
+	}
+	return out
+}
+
+func (s *Syncer) loadScoreWeights() model.ScoreWeights {
+	var setting model.SystemSetting
+	if err := s.db.First(&setting).Error; err != nil {
+		zap.S().Warnf("[snapshot] load system setting error: %v, using default score weights", err)
+		return mergeScoreWeights(nil)
+	}
+	return mergeScoreWeights(setting.ScoreWeights)
+}
+
+// mergeScoreWeights 以默认权重为基础，用配置中已知分项的非负权重覆盖
+func mergeScoreWeights(overrides model.ScoreWeights) model.ScoreWeights {
+	weights := make(model.ScoreWeights, len(defaultScoreWeights))
+	for k, v := range defaultScoreWeights {
+		weights[k] = v
+	}
+	for k, v := range overrides {
+		if _, ok := weights[k]; ok && v >= 0 {
+			weights[k] = v
+		}
+	}
+	return weights
+}
+
+// loadLastTradeTimes 每个交易员最近一笔已完成交易的平仓时间
+func (s *Syncer) loadLastTradeTimes() (map[string]int64, error) {
+	var rows []struct {
+		Address string
+		LastMs  int64
+	}
+	if err := s.db.Model(&model.CompletedTrade{}).
+		Select("address, MAX(end_time) AS last_ms").
+		Group("address").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		out[r.Address] = r.LastMs
+	}
+	return out, nil
+}
+
+// loadLiquidationCounts 按窗口统计被强平成交次数，返回 window -> address -> count
+func (s *Syncer) loadLiquidationCounts() (map[string]map[string]int, error) {
+	var rows []struct {
+		Win     string
+		Address string
+		Cnt     int
+	}
+	err := s.db.Raw(`
+		SELECT w.win, f.address, COUNT(*) AS cnt
+		FROM (VALUES ('day', ?::bigint), ('week', ?::bigint), ('month', ?::bigint), ('allTime', ?::bigint)) AS w(win, cutoff)
+		JOIN trader_fills f ON f.liquidation AND f.time >= w.cutoff
+		GROUP BY w.win, f.address`,
+		utility.WindowCutoff("day"), utility.WindowCutoff("week"),
+		utility.WindowCutoff("month"), utility.WindowCutoff("allTime"),
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]map[string]int)
+	for _, r := range rows {
+		if out[r.Win] == nil {
+			out[r.Win] = make(map[string]int)
+		}
+		out[r.Win][r.Address] = r.Cnt
+	}
+	return out, nil
+}
+
+func parseNum(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
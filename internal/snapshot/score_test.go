@@ -0,0 +1,148 @@
+package snapshot
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+func TestPercentileRanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   []float64
+	}{
+		{"empty", nil, []float64{}},
+		{"single", []float64{42}, []float64{1}},
+		{"ascending", []float64{1, 2, 3}, []float64{0, 0.5, 1}},
+		{"unordered", []float64{3, 1, 2}, []float64{1, 0, 0.5}},
+		{"ties share average rank", []float64{1, 1, 3}, []float64{0.25, 0.25, 1}},
+		{"all equal", []float64{5, 5, 5}, []float64{0.5, 0.5, 0.5}},
+		{"ties in the middle", []float64{0, 2, 2, 2, 9}, []float64{0, 0.5, 0.5, 0.5, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := percentileRanks(tt.values)
+			if len(got) != len(tt.want) {
+				t.Fatalf("percentileRanks(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > floatTolerance {
+					t.Fatalf("percentileRanks(%v) = %v, want %v", tt.values, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildScores(t *testing.T) {
+	full := func(v float64) map[string]float64 {
+		raw := make(map[string]float64, len(scoreComponents))
+		for _, c := range scoreComponents {
+			raw[c] = v
+		}
+		return raw
+	}
+	tests := []struct {
+		name      string
+		inputs    []scoreInput
+		weights   model.ScoreWeights
+		wantOrder []string
+		wantScore []float64 // 与 wantOrder 对应
+	}{
+		{
+			name:    "empty",
+			weights: defaultScoreWeights,
+		},
+		{
+			name:      "single trader",
+			inputs:    []scoreInput{{address: "a", raw: full(1)}},
+			weights:   defaultScoreWeights,
+			wantOrder: []string{"a"},
+			wantScore: []float64{100},
+		},
+		{
+			name:      "missing metrics count as zero",
+			inputs:    []scoreInput{{address: "b"}, {address: "a", raw: full(1)}},
+			weights:   defaultScoreWeights,
+			wantOrder: []string{"a", "b"},
+			wantScore: []float64{100, 0},
+		},
+		{
+			name: "weights decide the ranking",
+			inputs: []scoreInput{
+				{address: "a", raw: map[string]float64{"risk_adjusted": 2, "drawdown": -0.5}},
+				{address: "b", raw: map[string]float64{"risk_adjusted": 1, "drawdown": -0.1}},
+			},
+			weights:   mergeScoreWeights(model.ScoreWeights{"risk_adjusted": 0, "drawdown": 1}),
+			wantOrder: []string{"b", "a"},
+			// risk_adjusted 权重为 0，drawdown 权重 1，其余分项两人都缺失、打平各得 0.5
+			wantScore: []float64{
+				(1*1 + 0.5*(0.15+0.10+0.10+0.10+0.15)) / (1 + 0.15 + 0.10 + 0.10 + 0.10 + 0.15) * 100,
+				(0*1 + 0.5*(0.15+0.10+0.10+0.10+0.15)) / (1 + 0.15 + 0.10 + 0.10 + 0.10 + 0.15) * 100,
+			},
+		},
+		{
+			name:      "tied scores keep input order",
+			inputs:    []scoreInput{{address: "a", raw: full(1)}, {address: "b", raw: full(1)}},
+			weights:   defaultScoreWeights,
+			wantOrder: []string{"a", "b"},
+			wantScore: []float64{50, 50},
+		},
+		{
+			name:      "zero total weight",
+			inputs:    []scoreInput{{address: "a", raw: full(2)}, {address: "b", raw: full(1)}},
+			weights:   model.ScoreWeights{},
+			wantOrder: []string{"a", "b"},
+			wantScore: []float64{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := buildScores("allTime", tt.inputs, tt.weights)
+			if len(records) != len(tt.wantOrder) {
+				t.Fatalf("buildScores() returned %d records, want %d", len(records), len(tt.wantOrder))
+			}
+			for i, r := range records {
+				if r.Address != tt.wantOrder[i] || r.Rank != i+1 || r.Window != "allTime" {
+					t.Errorf("record #%d = %s rank %d window %s, want %s rank %d",
+						i, r.Address, r.Rank, r.Window, tt.wantOrder[i], i+1)
+				}
+				if got := parseNum(r.Score); math.Abs(got-tt.wantScore[i]) > 1e-6 {
+					t.Errorf("%s score = %v, want %v", r.Address, got, tt.wantScore[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeScoreWeights(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides model.ScoreWeights
+		want      model.ScoreWeights
+	}{
+		{"no setting", nil, defaultScoreWeights},
+		{
+			name:      "override known component",
+			overrides: model.ScoreWeights{"recency": 0.3, "liquidation": 0},
+			want: model.ScoreWeights{
+				"risk_adjusted": 0.25, "drawdown": 0.15, "consistency": 0.15, "sample_size": 0.10,
+				"recency": 0.3, "fee_drag": 0.10, "liquidation": 0,
+			},
+		},
+		{"negative and unknown ignored", model.ScoreWeights{"drawdown": -1, "luck": 5}, defaultScoreWeights},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeScoreWeights(tt.overrides); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeScoreWeights(%v) = %v, want %v", tt.overrides, got, tt.want)
+			}
+		})
+	}
+	if defaultScoreWeights["recency"] != 0.10 {
+		t.Error("mergeScoreWeights modified the defaults")
+	}
+}
@@ -2,110 +2,214 @@ package snapshot
 
 import (
 	"math/big"
-	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/hypercopy/crawler/internal/events"
 	"github.com/hypercopy/crawler/internal/hyperliquid"
 	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/proxy"
+	"github.com/hypercopy/crawler/internal/utility"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type Syncer struct {
-	db      *gorm.DB
-	workers int
+	db           *gorm.DB
+	rdb          *redis.Client
+	bus          *events.Bus
+	proxyMgr     *proxy.Manager
+	fetchWorkers int
+	statsWorkers int
+	limiter      *rateLimiter
+	labelRules   atomic.Pointer[model.LabelRuleSet]
+	sched        *scheduler
+	stats        *statsQueue
+
+	positioningRetention time.Duration // coin_positioning 保留时长，<= 0 不清理
+
+	refreshed   atomic.Int64 // 上次汇报以来的快照拉取次数
+	failed      atomic.Int64 // 上次汇报以来的拉取失败次数
+	computed    atomic.Int64 // 上次汇报以来的统计计算次数
+	statsFailed atomic.Int64 // 上次汇报以来的统计失败次数
 }
 
-func NewSyncer(db *gorm.DB, workers int) *Syncer {
+// 周期任务间隔
+const (
+	scheduleSyncInterval = time.Minute     // 同步交易员列表、调度信号、标签规则
+	derivedInterval      = 5 * time.Minute // 综合评分、百分位、聪明钱持仓指数等全量派生指标
+	pruneInterval        = time.Hour       // 快照历史降采样、聪明钱持仓指数清理
+)
+
+// NewSyncer fetchWorkers 为 API 拉取并发数，statsWorkers 为统计计算（数据库）并发数，
+// rps 为全部拉取 worker 合计的每秒请求数上限（<= 0 不限速），proxyMgr 为 nil 时直连，
+// positioningRetention 为聪明钱持仓指数保留时长（<= 0 不清理）
+func NewSyncer(db *gorm.DB, rdb *redis.Client, bus *events.Bus, proxyMgr *proxy.Manager, fetchWorkers, statsWorkers int, rps float64, positioningRetention time.Duration) *Syncer {
 	return &Syncer{
-		db:      db,
-		workers: workers,
+		db:           db,
+		rdb:          rdb,
+		bus:          bus,
+		proxyMgr:     proxyMgr,
+		fetchWorkers: fetchWorkers,
+		statsWorkers: statsWorkers,
+		limiter:      newRateLimiter(rps),
+		sched:        newScheduler(),
+		stats:        newStatsQueue(),
+
+		positioningRetention: positioningRetention,
 	}
 }
 
+// Run 按优先级持续刷新交易员：拉取 worker 从调度队列取出到期的交易员，拉取快照后按其活跃度重新排期，
+// 并把地址交给统计 worker 重算指标；评分、百分位、历史降采样等全量任务由定时器驱动
 func (s *Syncer) Run() {
-	for round := 1; ; round++ {
-		var traders []model.Trader
-		if err := s.db.Select("address").Find(&traders).Error; err != nil {
-			zap.S().Errorf("[snapshot] load traders error: %v, retrying in 10s", err)
-			time.Sleep(10 * time.Second)
-			continue
-		}
+	s.reloadLabelRules()
+	for !s.syncSchedule() {
+		time.Sleep(10 * time.Second)
+	}
+
+	for i := 0; i < s.fetchWorkers; i++ {
+		go s.fetchWorker(i)
+	}
+	for i := 0; i < s.statsWorkers; i++ {
+		go s.statsWorker()
+	}
 
-		total := int64(len(traders))
-		zap.S().Infof("[snapshot] round %d: %d traders to sync", round, total)
+	scheduleTicker := time.NewTicker(scheduleSyncInterval)
+	derivedTicker := time.NewTicker(derivedInterval)
+	pruneTicker := time.NewTicker(pruneInterval)
+	defer scheduleTicker.Stop()
+	defer derivedTicker.Stop()
+	defer pruneTicker.Stop()
 
-		addrCh := make(chan string, len(traders))
-		for _, t := range traders {
-			addrCh <- t.Address
-		}
-		close(addrCh)
-
-		var (
-			wg   sync.WaitGroup
-			done atomic.Int64
-			errs atomic.Int64
-		)
-
-		for i := 0; i < s.workers; i++ {
-			wg.Add(1)
-			go func(workerIdx int) {
-				defer wg.Done()
-				s.worker(workerIdx, addrCh, &done, &errs, total)
-			}(i)
+	for {
+		select {
+		case <-scheduleTicker.C:
+			s.reloadLabelRules()
+			s.syncSchedule()
+			zap.S().Infof("[snapshot] last %s: %d fetched (%d errors), %d stats computed (%d errors), %d stats queued, %d traders scheduled",
+				scheduleSyncInterval, s.refreshed.Swap(0), s.failed.Swap(0),
+				s.computed.Swap(0), s.statsFailed.Swap(0), s.stats.size(), s.sched.size())
+		case <-derivedTicker.C:
+			if err := s.updateScores(); err != nil {
+				zap.S().Errorf("[snapshot] update scores error: %v", err)
+			}
+			if err := s.updatePercentiles(); err != nil {
+				zap.S().Errorf("[snapshot] update percentiles error: %v", err)
+			}
+			if err := s.updatePositioning(); err != nil {
+				zap.S().Errorf("[snapshot] update positioning error: %v", err)
+			}
+		case <-pruneTicker.C:
+			if err := s.pruneSnapshotHistory(); err != nil {
+				zap.S().Errorf("[snapshot] prune snapshot history error: %v", err)
+			}
+			if err := s.prunePositioning(); err != nil {
+				zap.S().Errorf("[snapshot] prune positioning error: %v", err)
+			}
 		}
+	}
+}
+
+// syncSchedule 将 traders 表与调度队列对齐，并重新加载跟单/跟踪/成交活跃度信号
+func (s *Syncer) syncSchedule() bool {
+	var traders []model.Trader
+	if err := s.db.Select("address").Find(&traders).Error; err != nil {
+		zap.S().Errorf("[snapshot] load traders error: %v", err)
+		return false
+	}
+	addresses := make([]string, 0, len(traders))
+	for _, t := range traders {
+		addresses = append(addresses, t.Address)
+	}
+	added, removed := s.sched.sync(addresses)
+	if added > 0 || removed > 0 {
+		zap.S().Infof("[snapshot] schedule: +%d -%d traders, %d total", added, removed, len(addresses))
+	}
+
+	recentFills, trackers, copiers, err := s.loadScheduleSignals()
+	if err != nil {
+		zap.S().Errorf("[snapshot] load schedule signals error: %v", err)
+		return true
+	}
+	s.sched.setSignals(recentFills, trackers, copiers)
+	return true
+}
 
-		wg.Wait()
-		zap.S().Infof("[snapshot] round %d done: %d/%d succeeded, %d errors",
-			round, done.Load()-errs.Load(), total, errs.Load())
+func (s *Syncer) newClient(workerIdx int) *hyperliquid.Client {
+	if s.proxyMgr != nil {
+		client, err := s.proxyMgr.NewClientForWorker(workerIdx)
+		if err != nil {
+			zap.S().Warnf("[snapshot] worker %d: create proxy client error: %v, falling back to direct", workerIdx, err)
+			return hyperliquid.NewClient()
+		}
+		return client
 	}
+	return hyperliquid.NewClient()
 }
 
-func (s *Syncer) worker(workerIdx int, addrCh <-chan string, done, errs *atomic.Int64, total int64) {
-	client := hyperliquid.NewClient()
+// fetchWorker 拉取阶段：受全局速率限制调用 API，写入持仓与快照后交给统计阶段
+func (s *Syncer) fetchWorker(workerIdx int) {
+	client := s.newClient(workerIdx)
 
-	for address := range addrCh {
-		if err := s.processOne(client, address); err != nil {
-			zap.S().Warnf("[snapshot] %s error: %v", address[:10], err)
-			errs.Add(1)
+	for {
+		address := s.sched.next()
+		interval := errorRetryInterval
+		if err := s.fetchOne(client, address); err != nil {
+			zap.S().Warnf("[snapshot] worker %d %s error: %v", workerIdx, address[:10], err)
+			s.failed.Add(1)
+		} else {
+			interval = s.nextInterval(address)
+			s.stats.push(address)
 		}
-		cur := done.Add(1)
-		if cur%100 == 0 || cur == total {
-			zap.S().Infof("[snapshot] progress: %d/%d", cur, total)
+		s.refreshed.Add(1)
+		s.sched.reschedule(address, interval)
+	}
+}
+
+// statsWorker 统计阶段：只访问数据库
+func (s *Syncer) statsWorker() {
+	for {
+		address := s.stats.pop()
+		if err := s.updateStatistics(address); err != nil {
+			zap.S().Warnf("[snapshot] stats %s error: %v", address[:10], err)
+			s.statsFailed.Add(1)
 		}
+		s.computed.Add(1)
+		s.stats.done(address)
 	}
 }
 
-func (s *Syncer) processOne(client *hyperliquid.Client, address string) error {
+func (s *Syncer) fetchOne(client *hyperliquid.Client, address string) error {
+	s.limiter.wait()
 	chState, err := client.FetchClearinghouseState(address)
 	if err != nil {
 		return err
 	}
 
+	s.limiter.wait()
 	spotState, err := client.FetchSpotClearinghouseState(address)
 	if err != nil {
 		return err
 	}
 
-	if err := s.upsertPositions(address, chState.AssetPositions); err != nil {
+	risks, tr := calcRisk(chState)
+	if err := s.upsertPositions(address, chState.AssetPositions, risks); err != nil {
 		return err
 	}
 
-	if err := s.updateTraderSnap(address, chState, spotState); err != nil {
-		return err
-	}
-
-	return s.updateStatistics(address)
+	return s.updateTraderSnap(address, chState, spotState, tr)
 }
 
-func (s *Syncer) upsertPositions(address string, positions []model.AssetPosition) error {
+func (s *Syncer) upsertPositions(address string, positions []model.AssetPosition, risks map[string]positionRisk) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("address = ?", address).Delete(&model.TraderPosition{}).Error; err != nil {
+		var previous []model.TraderPosition
+		if err := tx.Where("address = ?", address).Find(&previous).Error; err != nil {
 			return err
 		}
-		if len(positions) == 0 {
-			return nil
+		if err := tx.Where("address = ?", address).Delete(&model.TraderPosition{}).Error; err != nil {
+			return err
 		}
 
 		records := make([]model.TraderPosition, 0, len(positions))
@@ -115,6 +219,7 @@ func (s *Syncer) upsertPositions(address string, positions []model.AssetPosition
 			if p.LiquidationPx != nil {
 				liqPx = *p.LiquidationPx
 			}
+			risk := risks[p.Coin]
 			records = append(records, model.TraderPosition{
 				Address:               address,
 				Coin:                  p.Coin,
@@ -131,13 +236,39 @@ func (s *Syncer) upsertPositions(address string, positions []model.AssetPosition
 				CumFundingAllTime:     p.CumFunding.AllTime,
 				CumFundingSinceOpen:   p.CumFunding.SinceOpen,
 				CumFundingSinceChange: p.CumFunding.SinceChange,
+				MarkPx:                utility.FmtFloat(risk.markPx),
+				LiqDistancePct:        risk.liqDistancePct,
+				Concentration:         utility.FmtFloat(risk.concentration),
 			})
 		}
-		return tx.Create(&records).Error
+		if len(records) > 0 {
+			if err := tx.Create(&records).Error; err != nil {
+				return err
+			}
+		}
+
+		// 首次同步没有上一次快照可比对，当前持仓不能视为新开仓；
+		// 快照历史每次同步都会写入且降采样不会删空，可据此区分"首次同步"与"上次为空仓"
+		if len(previous) == 0 {
+			var synced bool
+			if err := tx.Raw("SELECT EXISTS (SELECT 1 FROM trader_snapshot_history WHERE address = ?)", address).
+				Scan(&synced).Error; err != nil {
+				return err
+			}
+			if !synced {
+				return nil
+			}
+		}
+
+		events := diffPositions(address, previous, records, time.Now().UnixMilli())
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Create(&events).Error
 	})
 }
 
-func (s *Syncer) updateTraderSnap(address string, ch *model.ClearinghouseState, spot *model.SpotClearinghouseState) error {
+func (s *Syncer) updateTraderSnap(address string, ch *model.ClearinghouseState, spot *model.SpotClearinghouseState, risk traderRisk) error {
 	zero := new(big.Float)
 	longCount, shortCount := 0, 0
 	longValue := new(big.Float)
@@ -207,23 +338,53 @@ func (s *Syncer) updateTraderSnap(address string, ch *model.ClearinghouseState,
 	totalValue := new(big.Float).Add(accountValue, spotValue)
 
 	updates := map[string]interface{}{
-		"snap_eff_leverage":         effLeverage.Text('f', 10),
-		"snap_long_position_count":  longCount,
-		"snap_long_position_value":  longValue.Text('f', 10),
-		"snap_margin_usage_rate":    marginUsageRate.Text('f', 10),
-		"snap_perp_value":           ch.CrossMarginSummary.TotalNtlPos,
-		"snap_position_count":       longCount + shortCount,
-		"snap_position_value":       ch.MarginSummary.TotalNtlPos,
-		"snap_short_position_count": shortCount,
-		"snap_short_position_value": shortValue.Text('f', 10),
-		"snap_spot_value":           spotValue.Text('f', 10),
-		"snap_total_margin_used":    ch.MarginSummary.TotalMarginUsed,
-		"snap_total_value":          totalValue.Text('f', 10),
-		"snap_unrealized_pnl":       totalUnrealizedPnl.Text('f', 10),
-		"long_pnl":                  longPnl.Text('f', 10),
-		"short_pnl":                 shortPnl.Text('f', 10),
-		"total_pnl":                 new(big.Float).Add(longPnl, shortPnl).Text('f', 10),
-	}
-
-	return s.db.Model(&model.Trader{}).Where("address = ?", address).Updates(updates).Error
+		"snap_eff_leverage":           effLeverage.Text('f', 10),
+		"snap_long_position_count":    longCount,
+		"snap_long_position_value":    longValue.Text('f', 10),
+		"snap_margin_usage_rate":      marginUsageRate.Text('f', 10),
+		"snap_perp_value":             ch.CrossMarginSummary.TotalNtlPos,
+		"snap_position_count":         longCount + shortCount,
+		"snap_position_value":         ch.MarginSummary.TotalNtlPos,
+		"snap_short_position_count":   shortCount,
+		"snap_short_position_value":   shortValue.Text('f', 10),
+		"snap_spot_value":             spotValue.Text('f', 10),
+		"snap_total_margin_used":      ch.MarginSummary.TotalMarginUsed,
+		"snap_total_value":            totalValue.Text('f', 10),
+		"snap_unrealized_pnl":         totalUnrealizedPnl.Text('f', 10),
+		"long_pnl":                    longPnl.Text('f', 10),
+		"short_pnl":                   shortPnl.Text('f', 10),
+		"total_pnl":                   new(big.Float).Add(longPnl, shortPnl).Text('f', 10),
+		"snap_weighted_leverage":      utility.FmtFloat(risk.weightedLeverage),
+		"snap_max_concentration":      utility.FmtFloat(risk.maxConcentration),
+		"snap_max_concentration_coin": risk.maxConcentrationCoin,
+		"snap_min_liq_distance_pct":   risk.minLiqDistancePct,
+		"snap_min_liq_distance_coin":  risk.minLiqDistanceCoin,
+		"snap_cross_margin_buffer":    utility.FmtFloat(risk.crossMarginBuffer),
+	}
+
+	if err := s.db.Model(&model.Trader{}).Where("address = ?", address).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	return s.db.Create(&model.TraderSnapshotHistory{
+		Address:            address,
+		Time:               time.Now().UnixMilli(),
+		TotalValue:         totalValue.Text('f', 10),
+		PerpValue:          ch.CrossMarginSummary.TotalNtlPos,
+		SpotValue:          spotValue.Text('f', 10),
+		EffLeverage:        effLeverage.Text('f', 10),
+		MarginUsageRate:    marginUsageRate.Text('f', 10),
+		TotalMarginUsed:    ch.MarginSummary.TotalMarginUsed,
+		PositionValue:      ch.MarginSummary.TotalNtlPos,
+		LongPositionValue:  longValue.Text('f', 10),
+		ShortPositionValue: shortValue.Text('f', 10),
+		PositionCount:      longCount + shortCount,
+		LongPositionCount:  longCount,
+		ShortPositionCount: shortCount,
+		UnrealizedPnl:      totalUnrealizedPnl.Text('f', 10),
+		WeightedLeverage:   utility.FmtFloat(risk.weightedLeverage),
+		MaxConcentration:   utility.FmtFloat(risk.maxConcentration),
+		MinLiqDistancePct:  risk.minLiqDistancePct,
+		CrossMarginBuffer:  utility.FmtFloat(risk.crossMarginBuffer),
+	}).Error
 }
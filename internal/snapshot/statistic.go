@@ -4,9 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
-	"sort"
 	"strconv"
+	"time"
 
+	"github.com/hypercopy/crawler/internal/consts"
 	"github.com/hypercopy/crawler/internal/model"
 	"github.com/hypercopy/crawler/internal/utility"
 	"github.com/lib/pq"
@@ -21,8 +22,20 @@ func (s *Syncer) updateStatistics(address string) error {
 		return fmt.Errorf("load trader: %w", err)
 	}
 
-	var trades []model.CompletedTrade
-	s.db.Where("address = ?", address).Find(&trades)
+	// 水位线取在读取输入之前，计算期间写入的新数据会在下一轮被识别为变更
+	computedAt := time.Now()
+	dirty, err := s.statsDirty(&trader, computedAt)
+	if err != nil {
+		return fmt.Errorf("check stats watermark %s: %w", utility.Abbr(address), err)
+	}
+	if !dirty {
+		return s.refreshSnapStats(&trader)
+	}
+
+	aggs, err := loadTradeAggs(s.db, address)
+	if err != nil {
+		return fmt.Errorf("load trade aggs %s: %w", utility.Abbr(address), err)
+	}
 
 	avMap := make(map[string][]byte)
 	var accountValues []model.TraderAccountValue
@@ -31,19 +44,31 @@ func (s *Syncer) updateStatistics(address string) error {
 		avMap[av.Window] = []byte(av.History)
 	}
 
+	fees, err := s.loadFeeStats(address)
+	if err != nil {
+		return fmt.Errorf("load fee stats %s: %w", utility.Abbr(address), err)
+	}
+
+	overall := calcLabelMetrics(&trader, aggs["allTime"], avMap["allTime"])
+	rules := s.currentLabelRules()
+	sizeBucket := categoryLabel(rules, overall, consts.LabelCategoryAccountSize)
+
 	for _, window := range allWindows {
-		stat := buildStat(address, window, &trader, trades, avMap[window])
+		stat := buildStat(address, window, &trader, aggs[window], avMap[window], fees[window], overall)
+		stat.SizeBucket = sizeBucket
 		if err := s.upsertStat(&stat); err != nil {
 			return fmt.Errorf("upsert %s/%s: %w", utility.Abbr(address), window, err)
 		}
 	}
 
-	labels := computeLabels(&trader, trades, avMap["allTime"])
-	if labels == nil {
-		labels = []string{}
+	if err := s.updateCoinStatistics(address); err != nil {
+		return fmt.Errorf("update coin stats %s: %w", utility.Abbr(address), err)
 	}
-	if err := s.db.Model(&trader).Update("labels", pq.StringArray(labels)).Error; err != nil {
-		return fmt.Errorf("update labels %s: %w", utility.Abbr(address), err)
+
+	err = s.saveLabels(&trader, computeLabels(rules, overall), overall,
+		map[string]interface{}{"stats_computed_at": computedAt}, nil)
+	if err != nil {
+		return fmt.Errorf("save labels %s: %w", utility.Abbr(address), err)
 	}
 
 	return nil
@@ -71,60 +96,6 @@ type tradeStats struct {
 	coins []string
 }
 
-func calcTradeStats(trades []model.CompletedTrade, cutoff int64) tradeStats {
-	var ts tradeStats
-	total := 0
-	coinSet := make(map[string]struct{})
-
-	for _, t := range trades {
-		if t.EndTime < cutoff {
-			continue
-		}
-		total++
-		coinSet[t.Coin] = struct{}{}
-
-		if t.Pnl > 0 {
-			ts.profitCount++
-		}
-		ts.totalPnl += t.Pnl
-		ts.totalRealizedPnl += t.Pnl
-
-		switch t.Direction {
-		case "long":
-			ts.longCount++
-			ts.longPnl += t.Pnl
-			if t.Pnl > 0 {
-				ts.longProfitCount++
-			}
-		case "short":
-			ts.shortCount++
-			ts.shortPnl += t.Pnl
-			if t.Pnl > 0 {
-				ts.shortProfitCount++
-			}
-		}
-	}
-
-	if total > 0 {
-		ts.winRate = float64(ts.profitCount) / float64(total)
-	}
-	if ts.longCount > 0 {
-		ts.longWinRate = float64(ts.longProfitCount) / float64(ts.longCount)
-	}
-	if ts.shortCount > 0 {
-		ts.shortWinRate = float64(ts.shortProfitCount) / float64(ts.shortCount)
-	}
-
-
-	ts.coins = make([]string, 0, len(coinSet))
-	for c := range coinSet {
-		ts.coins = append(ts.coins, c)
-	}
-	sort.Strings(ts.coins)
-
-	return ts
-}
-
 // ── sharpe & drawdown (from account value history) ──────────────────
 
 func parseTimeSeries(data []byte) [][2]float64 {
@@ -205,19 +176,80 @@ func calcMaxDrawdown(history []byte) float64 {
 	return maxDD
 }
 
+// calcSortino 与 calcSharpe 相同的日收益序列，分母只取下行波动
+func calcSortino(history []byte) float64 {
+	series := parseTimeSeries(history)
+	if len(series) < 3 {
+		return 0
+	}
+
+	returns := make([]float64, 0, len(series)-1)
+	for i := 1; i < len(series); i++ {
+		prev := series[i-1][1]
+		if prev == 0 {
+			continue
+		}
+		returns = append(returns, (series[i][1]-prev)/math.Abs(prev))
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+
+	sum := 0.0
+	downSq := 0.0
+	for _, r := range returns {
+		sum += r
+		if r < 0 {
+			downSq += r * r
+		}
+	}
+	mean := sum / float64(len(returns))
+	downside := math.Sqrt(downSq / float64(len(returns)))
+	if downside == 0 {
+		return 0
+	}
+
+	return (mean / downside) * math.Sqrt(365)
+}
+
+// calcCalmar 年化收益率（按区间收益线性年化）/ 最大回撤
+func calcCalmar(history []byte) float64 {
+	series := parseTimeSeries(history)
+	if len(series) < 2 {
+		return 0
+	}
+
+	first, last := series[0], series[len(series)-1]
+	days := (last[0] - first[0]) / msPerDay
+	if first[1] <= 0 || days <= 0 {
+		return 0
+	}
+	maxDD := calcMaxDrawdown(history)
+	if maxDD == 0 {
+		return 0
+	}
+
+	annualReturn := (last[1] - first[1]) / first[1] * (365 / days)
+	return annualReturn / maxDD
+}
+
 // ── assemble stat row ───────────────────────────────────────────────
 
 func buildStat(
 	address, window string,
 	trader *model.Trader,
-	allTrades []model.CompletedTrade,
+	agg tradeAgg,
 	accountValueHistory []byte,
+	fee feeStats,
+	overall labelMetrics,
 ) model.TraderStatistic {
-	cutoff := utility.WindowCutoff(window)
-	ts := calcTradeStats(allTrades, cutoff)
+	ts := agg.stats()
 	sharpe := calcSharpe(accountValueHistory)
 	drawdown := calcMaxDrawdown(accountValueHistory)
 
+	// 交易维度指标按窗口内的交易计算；90天活跃、近30天滚动收益、90天回撤本身有固定周期，取全量结果
+	lm := calcLabelMetrics(trader, agg, nil)
+
 	return model.TraderStatistic{
 		Address:            address,
 		Window:             window,
@@ -240,10 +272,26 @@ func buildStat(
 		ShortCount:         strconv.Itoa(ts.shortCount),
 		ShortRealizedPnl:   utility.FmtFloat(ts.shortPnl),
 		ShortWinRate:       utility.FmtFloat(ts.shortWinRate),
-		UnrealizedPnl:       utility.OrZero(trader.SnapUnrealizedPnl),
-		AvgLeverage:         utility.OrZero(trader.SnapEffLeverage),
-		TotalRealizedPnl:    utility.FmtFloat(ts.totalRealizedPnl),
-		Coins:               pq.StringArray(ts.coins),
+		UnrealizedPnl:      utility.OrZero(trader.SnapUnrealizedPnl),
+		AvgLeverage:        utility.OrZero(trader.SnapEffLeverage),
+		TotalRealizedPnl:   utility.FmtFloat(ts.totalRealizedPnl),
+		Volume:             utility.FmtFloat(fee.volume),
+		TotalFee:           utility.FmtFloat(fee.feePaid),
+		MakerRebate:        utility.FmtFloat(fee.makerRebate),
+		NetFee:             utility.FmtFloat(fee.netFee()),
+		FeeRate:            utility.FmtFloat(fee.feeRate()),
+		FeePnlRatio:        utility.FmtFloat(fee.feePnlRatio()),
+		ProfitFactor:       utility.FmtFloat(lm.profitFactor),
+		Expectancy:         utility.FmtFloat(lm.expectedValue),
+		RiskRewardRatio:    utility.FmtFloat(lm.riskRewardRatio),
+		AvgHoldingMs:       utility.FmtFloat(lm.avgHoldingMs),
+		AvgProfitPerWin:    utility.FmtFloat(lm.avgProfitPerWin),
+		ActiveDays90:       strconv.Itoa(overall.activeDays90),
+		Rolling30dReturn:   utility.FmtFloat(overall.rolling30Return),
+		Drawdown90:         utility.FmtFloat(overall.maxDrawdown90),
+		Sortino:            utility.FmtFloat(calcSortino(accountValueHistory)),
+		Calmar:             utility.FmtFloat(calcCalmar(accountValueHistory)),
+		Coins:              pq.StringArray(ts.coins),
 	}
 }
 
@@ -259,6 +307,10 @@ func (s *Syncer) upsertStat(stat *model.TraderStatistic) error {
 			"total_pnl", "long_count", "long_realized_pnl", "long_win_rate",
 			"short_count", "short_realized_pnl", "short_win_rate",
 			"unrealized_pnl", "avg_leverage", "total_realized_pnl",
+			"volume", "total_fee", "maker_rebate", "net_fee", "fee_rate", "fee_pnl_ratio",
+			"profit_factor", "expectancy", "risk_reward_ratio", "avg_holding_ms",
+			"avg_profit_per_win", "active_days90", "rolling30d_return", "drawdown90",
+			"sortino", "calmar", "size_bucket",
 			"coins", "updated_at",
 		}),
 	}).Create(stat).Error
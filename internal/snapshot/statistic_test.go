@@ -0,0 +1,56 @@
+package snapshot
+
+import (
+	"math"
+	"testing"
+)
+
+const floatTolerance = 1e-9
+
+func TestCalcSortino(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    float64
+	}{
+		{"empty", ``, 0},
+		{"too few points", `[[0,"100"],[86400000,"110"]]`, 0},
+		{"no downside", `[[0,"100"],[86400000,"110"],[172800000,"121"]]`, 0},
+		{"mixed returns", `[[0,"100"],[86400000,"110"],[172800000,"99"],[259200000,"108.9"]]`, 11.030261405182863},
+		{"zero values skipped", `[[0,"0"],[86400000,"100"],[172800000,"90"],[259200000,"99"]]`, 0},
+		{"all losses", `[[0,100],[86400000,90],[172800000,81]]`, -math.Sqrt(365)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calcSortino([]byte(tt.history)); math.Abs(got-tt.want) > floatTolerance {
+				t.Errorf("calcSortino() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcCalmar(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    float64
+	}{
+		{"empty", ``, 0},
+		{"single point", `[[0,"100"]]`, 0},
+		{"no drawdown", `[[0,"100"],[31536000000,"150"]]`, 0},
+		{"non-positive start", `[[0,"0"],[86400000,"50"],[31536000000,"150"]]`, 0},
+		{"same timestamp", `[[0,"100"],[0,"50"]]`, 0},
+		// 一年内 100 -> 50 -> 150：年化收益 50%，最大回撤 50%
+		{"one year", `[[0,"100"],[15768000000,"50"],[31536000000,"150"]]`, 1},
+		// 半年内 100 -> 80 -> 110：年化收益 20%，最大回撤 20%
+		{"half year", `[[0,"100"],[7884000000,"80"],[15768000000,"110"]]`, 1},
+		{"losing year", `[[0,"100"],[31536000000,"75"]]`, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calcCalmar([]byte(tt.history)); math.Abs(got-tt.want) > floatTolerance {
+				t.Errorf("calcCalmar() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
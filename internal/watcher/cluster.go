@@ -0,0 +1,207 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	clusterKey        = "watcher:instances" // ZSET，成员为实例ID，score 为最近一次心跳（Redis 服务器时间，毫秒时间戳）
+	heartbeatInterval = 5 * time.Second
+	instanceTTL       = 20 * time.Second // 超过该时长没有心跳的实例视为已退出
+)
+
+// cluster 多个 watcher 实例通过 Redis 心跳互相发现，用 rendezvous hashing 划分地址：
+// 每个地址归属于 hash(实例ID|地址) 最大的存活实例，实例加入或退出时只有其负责的地址会迁移
+type cluster struct {
+	rdb *redis.Client
+	id  string
+
+	mu       sync.RWMutex
+	members  []string
+	isolated bool      // 心跳连续失败超过 instanceTTL，其他实例已接管本实例的地址
+	lastBeat time.Time // 最近一次心跳成功的本地时间
+
+	version atomic.Int64  // 成员变化次数
+	changed chan struct{} // 成员变化通知（容量 1，不堆积）
+
+	stop chan struct{} // 关闭后停止心跳
+	done chan struct{} // 心跳循环退出后关闭
+}
+
+// JoinCluster 以 id 注册为集群实例并开始心跳，之后 loadLeaders 只返回本实例负责的地址
+func (w *Watcher) JoinCluster(id string) error {
+	c := &cluster{
+		rdb:     w.rdb,
+		id:      id,
+		changed: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := c.heartbeat(); err != nil {
+		return fmt.Errorf("join cluster: %w", err)
+	}
+	w.cluster = c
+	go c.run()
+	return nil
+}
+
+// LeaveCluster 停止心跳并从集群中移除本实例，其他实例在下一次心跳时接管其地址；未加入集群时不做任何事。
+// 只应在进程退出前调用一次
+func (w *Watcher) LeaveCluster() {
+	if w.cluster == nil {
+		return
+	}
+	close(w.cluster.stop)
+	<-w.cluster.done
+	if err := w.rdb.ZRem(context.Background(), clusterKey, w.cluster.id).Err(); err != nil {
+		zap.S().Errorf("[watcher] leave cluster error: %v", err)
+	}
+}
+
+func (c *cluster) run() {
+	defer close(c.done)
+	t := time.NewTicker(heartbeatInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-c.stop:
+			return
+		}
+		if err := c.heartbeat(); err != nil {
+			zap.S().Errorf("[watcher] cluster heartbeat error: %v", err)
+			c.checkIsolated()
+		}
+	}
+}
+
+// checkIsolated 心跳失败超过 instanceTTL 时其他实例已把本实例视为退出并接管其地址，
+// 此时不再负责任何地址，直到心跳恢复
+func (c *cluster) checkIsolated() {
+	c.mu.Lock()
+	if c.isolated || time.Since(c.lastBeat) <= instanceTTL {
+		c.mu.Unlock()
+		return
+	}
+	c.isolated = true
+	c.members = nil
+	c.mu.Unlock()
+
+	zap.S().Warnf("[watcher] cluster heartbeat failing for over %v, releasing all addresses (self=%s)", instanceTTL, c.id)
+	c.notify()
+}
+
+func (c *cluster) notify() {
+	c.version.Add(1)
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// heartbeat 刷新本实例心跳、清理过期实例，并更新存活成员列表；
+// 时间取 Redis 服务器时间，避免实例间时钟偏差导致误判过期
+func (c *cluster) heartbeat() error {
+	ctx := context.Background()
+	now, err := c.rdb.Time(ctx).Result()
+	if err != nil {
+		return fmt.Errorf("redis time: %w", err)
+	}
+
+	pipe := c.rdb.TxPipeline()
+	pipe.ZAdd(ctx, clusterKey, redis.Z{Score: float64(now.UnixMilli()), Member: c.id})
+	pipe.ZRemRangeByScore(ctx, clusterKey, "-inf", strconv.FormatInt(now.Add(-instanceTTL).UnixMilli(), 10))
+	members := pipe.ZRange(ctx, clusterKey, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	m := members.Val()
+	slices.Sort(m)
+
+	c.mu.Lock()
+	changed := c.isolated || !slices.Equal(m, c.members)
+	c.members = m
+	c.isolated = false
+	c.lastBeat = time.Now()
+	c.mu.Unlock()
+
+	if changed {
+		zap.S().Infof("[watcher] cluster members changed: %d instances %v (self=%s)", len(m), m, c.id)
+		c.notify()
+	}
+	return nil
+}
+
+// owns 判断地址是否归本实例负责；与集群失联时不负责任何地址，尚未获取到成员列表时视为单实例
+func (c *cluster) owns(address string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.isolated {
+		return false
+	}
+	if len(c.members) == 0 {
+		return true
+	}
+
+	addr := strings.ToLower(address)
+	var best string
+	var bestHash uint64
+	for _, m := range c.members {
+		if sum := rendezvousHash(m, addr); best == "" || sum > bestHash {
+			best, bestHash = m, sum
+		}
+	}
+	return best == c.id
+}
+
+// rendezvousHash 实例与地址的权重。FNV-1a 的结果高位主要由前缀（实例ID）决定，
+// 直接比较会让同一个实例赢下几乎所有地址，需再经 murmur3 fmix64 打散
+func rendezvousHash(member, addr string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(member + "|" + addr))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func (c *cluster) filter(addresses []string) []string {
+	out := addresses[:0:0]
+	for _, a := range addresses {
+		if c.owns(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// clusterVersion 未加入集群时恒为 0
+func (w *Watcher) clusterVersion() int64 {
+	if w.cluster == nil {
+		return 0
+	}
+	return w.cluster.version.Load()
+}
+
+// clusterChanged 未加入集群时返回 nil channel（永远阻塞）
+func (w *Watcher) clusterChanged() <-chan struct{} {
+	if w.cluster == nil {
+		return nil
+	}
+	return w.cluster.changed
+}
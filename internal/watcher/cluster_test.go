@@ -0,0 +1,95 @@
+package watcher
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testCluster(id string, members ...string) *cluster {
+	return &cluster{id: id, members: members, changed: make(chan struct{}, 1)}
+}
+
+func TestClusterOwns(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        *cluster
+		address  string
+		wantOwns bool
+	}{
+		{"no members yet", testCluster("a"), "0xabc", true},
+		{"only self", testCluster("a", "a"), "0xabc", true},
+		{"self not registered", testCluster("a", "b"), "0xabc", false},
+		{"isolated", &cluster{id: "a", members: nil, isolated: true}, "0xabc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.owns(tt.address); got != tt.wantOwns {
+				t.Errorf("owns(%q) = %v, want %v", tt.address, got, tt.wantOwns)
+			}
+		})
+	}
+}
+
+func TestClusterPartition(t *testing.T) {
+	members := []string{"a", "b", "c"}
+	addresses := make([]string, 300)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("0x%040x", i)
+	}
+
+	owner := func(members []string, addr string) string {
+		var found string
+		for _, id := range members {
+			if testCluster(id, members...).owns(addr) {
+				if found != "" {
+					t.Fatalf("%s owned by both %s and %s", addr, found, id)
+				}
+				found = id
+			}
+		}
+		if found == "" {
+			t.Fatalf("%s has no owner", addr)
+		}
+		return found
+	}
+
+	before := make(map[string]string, len(addresses))
+	counts := make(map[string]int)
+	for _, a := range addresses {
+		before[a] = owner(members, a)
+		counts[before[a]]++
+	}
+	// 300 个地址分给 3 个实例，每个实例至少应分到 1/5
+	for _, id := range members {
+		if counts[id] < len(addresses)/5 {
+			t.Errorf("unbalanced partition: %v", counts)
+			break
+		}
+	}
+
+	// 地址大小写不影响归属
+	for _, a := range addresses[:10] {
+		upper := "0X" + a[2:]
+		if got := owner(members, upper); got != before[a] {
+			t.Errorf("owner(%s) = %s, want %s", upper, got, before[a])
+		}
+	}
+
+	// 实例退出时只有它负责的地址迁移
+	remaining := []string{"a", "c"}
+	for _, a := range addresses {
+		got := owner(remaining, a)
+		if before[a] != "b" && got != before[a] {
+			t.Errorf("%s moved from %s to %s after b left", a, before[a], got)
+		}
+	}
+}
+
+func TestClusterFilter(t *testing.T) {
+	addresses := []string{"0x1", "0x2", "0x3", "0x4", "0x5"}
+	a, b := testCluster("a", "a", "b"), testCluster("b", "a", "b")
+	got := len(a.filter(addresses)) + len(b.filter(addresses))
+	if got != len(addresses) {
+		t.Errorf("filter split %d addresses into %d", len(addresses), got)
+	}
+}
@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"go.uber.org/zap"
+)
+
+// EventOptions 新开仓事件的补充信息与发布过滤条件，过滤条件为零值时不过滤
+type EventOptions struct {
+	StatsWindow   string   // 补充 trader_statistics 指标使用的窗口（day/week/month/allTime）
+	MinValue      float64  // 持仓价值低于该值（USD）不发布
+	MinAccountPct float64  // 持仓价值占账户价值百分比低于该值不发布
+	Labels        []string // 交易员至少带有其中一个标签才发布
+}
+
+// SetEventOptions 设置新开仓事件的补充与过滤选项
+func (w *Watcher) SetEventOptions(opts EventOptions) {
+	if opts.StatsWindow == "" {
+		opts.StatsWindow = "allTime"
+	}
+	w.eventOpts = opts
+}
+
+func (o EventOptions) allow(evt NewPositionEvent) bool {
+	if o.MinValue > 0 {
+		if v, _ := strconv.ParseFloat(evt.PositionValue, 64); v < o.MinValue {
+			return false
+		}
+	}
+	if o.MinAccountPct > 0 && evt.PositionPctOfAccount < o.MinAccountPct {
+		return false
+	}
+	if len(o.Labels) > 0 {
+		return slices.ContainsFunc(evt.Labels, func(l string) bool {
+			return slices.Contains(o.Labels, l)
+		})
+	}
+	return true
+}
+
+// enrich 补充交易员标签、评分、统计指标及账户占比，交易员不在 traders / trader_statistics 中时对应字段为空
+func (w *Watcher) enrich(evt *NewPositionEvent, chState *model.ClearinghouseState) {
+	evt.AccountValue = chState.MarginSummary.AccountValue
+	value, _ := strconv.ParseFloat(evt.PositionValue, 64)
+	if av, _ := strconv.ParseFloat(evt.AccountValue, 64); av > 0 {
+		evt.PositionPctOfAccount = value / av * 100
+	}
+	if szi, _ := strconv.ParseFloat(evt.Szi, 64); szi != 0 {
+		evt.MarkPx = utility.FmtFloat(value / math.Abs(szi))
+	}
+
+	var trader model.Trader
+	if err := w.db.Select("username", "twitter_name", "labels").
+		Where("address = ?", evt.Address).Limit(1).Find(&trader).Error; err != nil {
+		zap.S().Warnf("[watcher] load trader %s error: %v", utility.Abbr(evt.Address), err)
+	}
+	evt.Username = trader.Username
+	evt.TwitterName = trader.TwitterName
+	evt.Labels = []string(trader.Labels)
+	if evt.Labels == nil {
+		evt.Labels = []string{}
+	}
+
+	var stat model.TraderStatistic
+	if err := w.db.Select("win_rate", "total_pnl", "sharpe").
+		Where(`address = ? AND "window" = ?`, evt.Address, w.eventOpts.StatsWindow).Limit(1).Find(&stat).Error; err != nil {
+		zap.S().Warnf("[watcher] load statistic %s error: %v", utility.Abbr(evt.Address), err)
+	}
+	evt.StatsWindow = w.eventOpts.StatsWindow
+	evt.WinRate = stat.WinRate
+	evt.TotalPnl = stat.TotalPnl
+	evt.Sharpe = stat.Sharpe
+
+	if err := w.db.Model(&model.TraderScore{}).
+		Where(`address = ? AND "window" = ?`, evt.Address, "allTime").
+		Select("COALESCE(score, 0)").
+		Scan(&evt.Score).Error; err != nil {
+		zap.S().Warnf("[watcher] load score %s error: %v", utility.Abbr(evt.Address), err)
+	}
+}
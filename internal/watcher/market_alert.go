@@ -0,0 +1,165 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/events"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	marketTimelinePrefix = "watcher:new_position_timeline:" // + coin:direction，成员为 address|value|score|毫秒时间戳
+	marketCooldownPrefix = "watcher:market_alert_cooldown:" // + coin:direction，同一币种方向一个时间窗口内只预警一次
+)
+
+// MarketAlertTrader 参与预警的交易员，同一地址在窗口内多次开仓时价值累加
+type MarketAlertTrader struct {
+	Address string  `json:"address"`
+	Value   float64 `json:"value"`
+	Score   float64 `json:"score"` // allTime 综合评分（0~100），无评分为 0
+}
+
+// MarketAlert 同一币种、同一方向在时间窗口内的集中开仓预警
+type MarketAlert struct {
+	Count         int64               `json:"count"` // 窗口内开仓的不同交易员数
+	Minutes       int                 `json:"minutes"`
+	Threshold     int                 `json:"threshold"`
+	Coin          string              `json:"coin"`
+	Direction     string              `json:"direction"` // long/short
+	TotalValue    float64             `json:"totalValue"`
+	WeightedCount float64             `json:"weightedCount"` // Σ 评分/100
+	MinScore      float64             `json:"minScore"`
+	Traders       []MarketAlertTrader `json:"traders"` // 按价值降序
+	Message       string              `json:"message"`
+}
+
+// trackMarket 将新开仓记入所属币种、方向的时间窗口，满足 system_setting 中的数量（可按评分加权）与价值阈值时发布预警
+func (w *Watcher) trackMarket(evt NewPositionEvent, setting *model.SystemSetting) {
+	ctx := context.Background()
+
+	minScore, _ := strconv.ParseFloat(setting.MarketMinScore, 64)
+	minValue, _ := strconv.ParseFloat(setting.MarketMinValue, 64)
+	score := evt.Score
+	if minScore > 0 && score < minScore {
+		return
+	}
+
+	szi, _ := strconv.ParseFloat(evt.Szi, 64)
+	direction := "long"
+	if szi < 0 {
+		direction = "short"
+	}
+	value, _ := strconv.ParseFloat(evt.PositionValue, 64)
+
+	key := marketTimelinePrefix + evt.Coin + ":" + direction
+	window := time.Duration(setting.MarketMinutes) * time.Minute
+	now := time.Now().UnixMilli()
+	member := fmt.Sprintf("%s|%g|%g|%d", evt.Address, value, score, now)
+
+	pipe := w.rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now), Member: member})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now-window.Milliseconds(), 10))
+	pipe.Expire(ctx, key, 2*window)
+	members := pipe.ZRange(ctx, key, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		zap.S().Errorf("[watcher] redis timeline %s error: %v", key, err)
+		return
+	}
+
+	alert := aggregateTimeline(members.Val())
+	alert.Minutes = setting.MarketMinutes
+	alert.Threshold = setting.MarketNewPositionCount
+	alert.Coin = evt.Coin
+	alert.Direction = direction
+	alert.MinScore = minScore
+
+	measure := float64(alert.Count)
+	if setting.MarketScoreWeighted {
+		measure = alert.WeightedCount
+	}
+	zap.S().Infof("[watcher] %s %s timeline: %d traders (weighted %.2f), $%.0f in %dmin",
+		evt.Coin, direction, alert.Count, alert.WeightedCount, alert.TotalValue, setting.MarketMinutes)
+
+	if measure < float64(setting.MarketNewPositionCount) || (minValue > 0 && alert.TotalValue < minValue) {
+		return
+	}
+
+	ok, err := w.rdb.SetNX(ctx, marketCooldownPrefix+evt.Coin+":"+direction, now, window).Result()
+	if err != nil {
+		zap.S().Errorf("[watcher] redis market alert cooldown error: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	w.publishMarketAlert(alert)
+}
+
+// aggregateTimeline 按地址汇总时间窗口内的开仓记录
+func aggregateTimeline(members []string) MarketAlert {
+	byAddr := make(map[string]*MarketAlertTrader)
+	for _, m := range members {
+		parts := strings.Split(m, "|")
+		if len(parts) != 4 {
+			continue
+		}
+		value, _ := strconv.ParseFloat(parts[1], 64)
+		score, _ := strconv.ParseFloat(parts[2], 64)
+		t, ok := byAddr[parts[0]]
+		if !ok {
+			t = &MarketAlertTrader{Address: parts[0]}
+			byAddr[parts[0]] = t
+		}
+		t.Value += value
+		t.Score = max(t.Score, score)
+	}
+
+	var alert MarketAlert
+	alert.Traders = make([]MarketAlertTrader, 0, len(byAddr))
+	for _, t := range byAddr {
+		alert.Traders = append(alert.Traders, *t)
+		alert.TotalValue += t.Value
+		alert.WeightedCount += t.Score / 100
+	}
+	sort.Slice(alert.Traders, func(i, j int) bool {
+		return alert.Traders[i].Value > alert.Traders[j].Value
+	})
+	alert.Count = int64(len(alert.Traders))
+	return alert
+}
+
+func (w *Watcher) publishMarketAlert(alert MarketAlert) {
+	who := "traders"
+	if alert.MinScore > 0 {
+		who = "top traders"
+	}
+	alert.Message = fmt.Sprintf("%d %s opened %s %ss worth $%s in %d minutes",
+		alert.Count, who, alert.Coin, alert.Direction, humanUSD(alert.TotalValue), alert.Minutes)
+
+	if err := w.bus.Publish(context.Background(), marketAlertChannel, events.MarketAlert, alert); err != nil {
+		zap.S().Errorf("[watcher] redis publish market alert error: %v", err)
+		return
+	}
+
+	zap.S().Warnf("[watcher] MARKET ALERT: %s (threshold=%d)", alert.Message, alert.Threshold)
+}
+
+// humanUSD 12345678 -> 12.3M
+func humanUSD(v float64) string {
+	switch {
+	case v >= 1e9:
+		return strconv.FormatFloat(v/1e9, 'f', 1, 64) + "B"
+	case v >= 1e6:
+		return strconv.FormatFloat(v/1e6, 'f', 1, 64) + "M"
+	case v >= 1e3:
+		return strconv.FormatFloat(v/1e3, 'f', 1, 64) + "K"
+	}
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
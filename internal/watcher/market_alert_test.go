@@ -0,0 +1,76 @@
+package watcher
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAggregateTimeline(t *testing.T) {
+	tests := []struct {
+		name      string
+		members   []string
+		traders   []MarketAlertTrader
+		total     float64
+		weighted  float64
+		wantCount int64
+	}{
+		{
+			name:    "empty",
+			traders: []MarketAlertTrader{},
+		},
+		{
+			name:      "sorted by value",
+			members:   []string{"0xa|1000|50|1", "0xb|5000|80|2"},
+			traders:   []MarketAlertTrader{{"0xb", 5000, 80}, {"0xa", 1000, 50}},
+			total:     6000,
+			weighted:  1.3,
+			wantCount: 2,
+		},
+		{
+			name:      "same address merged, max score",
+			members:   []string{"0xa|1000|40|1", "0xa|2500|60|2", "0xb|3000|0|3"},
+			traders:   []MarketAlertTrader{{"0xa", 3500, 60}, {"0xb", 3000, 0}},
+			total:     6500,
+			weighted:  0.6,
+			wantCount: 2,
+		},
+		{
+			name:      "malformed members skipped",
+			members:   []string{"0xa|1000|40", "garbage", "0xb|1e+06|100|5"},
+			traders:   []MarketAlertTrader{{"0xb", 1e6, 100}},
+			total:     1e6,
+			weighted:  1,
+			wantCount: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := aggregateTimeline(tt.members)
+			if !reflect.DeepEqual(got.Traders, tt.traders) {
+				t.Errorf("traders = %+v, want %+v", got.Traders, tt.traders)
+			}
+			if got.Count != tt.wantCount || got.TotalValue != tt.total || math.Abs(got.WeightedCount-tt.weighted) > 1e-9 {
+				t.Errorf("count/total/weighted = %d/%v/%v, want %d/%v/%v",
+					got.Count, got.TotalValue, got.WeightedCount, tt.wantCount, tt.total, tt.weighted)
+			}
+		})
+	}
+}
+
+func TestHumanUSD(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{999, "999"},
+		{1000, "1.0K"},
+		{12345678, "12.3M"},
+		{2.5e9, "2.5B"},
+	}
+	for _, tt := range tests {
+		if got := humanUSD(tt.v); got != tt.want {
+			t.Errorf("humanUSD(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package watcher
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/hypercopy/crawler/internal/events"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"go.uber.org/zap"
+)
+
+// 持仓变更事件类型，每种类型发布到独立的 Redis 频道
+const (
+	PositionChangeClosed          = "closed"
+	PositionChangeSizeChanged     = "size_changed"
+	PositionChangeFlipped         = "flipped"
+	PositionChangeLeverageChanged = "leverage_changed"
+)
+
+var positionChangeTypes = map[string]events.Type{
+	PositionChangeClosed:          events.PositionClosed,
+	PositionChangeSizeChanged:     events.PositionSizeChanged,
+	PositionChangeFlipped:         events.PositionFlipped,
+	PositionChangeLeverageChanged: events.PositionLeverageChanged,
+}
+
+// PositionChangeEvent 已有持仓的变更事件，Old* 为上一轮观测值、New* 为本轮观测值（平仓时 New* 为空）
+type PositionChangeEvent struct {
+	Type            string  `json:"type"`
+	Address         string  `json:"address"`
+	Coin            string  `json:"coin"`
+	OldSzi          string  `json:"oldSzi"`
+	NewSzi          string  `json:"newSzi"`
+	SizeChangePct   float64 `json:"sizeChangePct"` // (|新仓位|-|旧仓位|)/|旧仓位|×100，仅 size_changed 有意义
+	OldLeverage     int     `json:"oldLeverage"`
+	NewLeverage     int     `json:"newLeverage"`
+	OldLeverageType string  `json:"oldLeverageType"`
+	NewLeverageType string  `json:"newLeverageType"`
+	OldEntryPx      string  `json:"oldEntryPx"`
+	NewEntryPx      string  `json:"newEntryPx"`
+	PositionValue   string  `json:"positionValue"`
+	Time            int64   `json:"time"`
+}
+
+// diffHolding 对比上一轮持仓与本轮 clearinghouseState，生成平仓、仓位变化、反手、调杠杆事件；
+// 新开仓由 processOne 单独生成 NewPositionEvent。
+// 仓位绝对值变化比例达到 sizeChangePct（百分比）才记为 size_changed，sizeChangePct <= 0 时任何变化都记录，
+// 仓位大小未变时不记录；反手时不再重复生成 size_changed。
+// 旧数据没有记录杠杆（为 0）时不判断杠杆变化。
+func diffHolding(address string, oldCoins map[string]model.CoinPosition, current []model.AssetPosition, sizeChangePct float64, nowMs int64) []PositionChangeEvent {
+	var events []PositionChangeEvent
+	seen := make(map[string]bool, len(current))
+
+	for _, ap := range current {
+		p := ap.Position
+		seen[p.Coin] = true
+		old, exists := oldCoins[p.Coin]
+		if !exists {
+			continue
+		}
+
+		event := func(eventType string) PositionChangeEvent {
+			return PositionChangeEvent{
+				Type:            eventType,
+				Address:         address,
+				Coin:            p.Coin,
+				OldSzi:          old.Szi,
+				NewSzi:          p.Szi,
+				OldLeverage:     old.Leverage,
+				NewLeverage:     p.Leverage.Value,
+				OldLeverageType: old.LeverageType,
+				NewLeverageType: p.Leverage.Type,
+				OldEntryPx:      old.EntryPx,
+				NewEntryPx:      p.EntryPx,
+				PositionValue:   p.PositionValue,
+				Time:            nowMs,
+			}
+		}
+
+		oldSzi, _ := strconv.ParseFloat(old.Szi, 64)
+		newSzi, _ := strconv.ParseFloat(p.Szi, 64)
+		switch {
+		case oldSzi != 0 && newSzi != 0 && (oldSzi > 0) != (newSzi > 0):
+			events = append(events, event(PositionChangeFlipped))
+		case oldSzi != 0:
+			pct := (math.Abs(newSzi) - math.Abs(oldSzi)) / math.Abs(oldSzi) * 100
+			if pct != 0 && math.Abs(pct) >= sizeChangePct {
+				e := event(PositionChangeSizeChanged)
+				e.SizeChangePct = pct
+				events = append(events, e)
+			}
+		}
+
+		if old.Leverage > 0 && old.Leverage != p.Leverage.Value {
+			events = append(events, event(PositionChangeLeverageChanged))
+		}
+	}
+
+	var closed []string
+	for coin := range oldCoins {
+		if !seen[coin] {
+			closed = append(closed, coin)
+		}
+	}
+	sort.Strings(closed)
+	for _, coin := range closed {
+		old := oldCoins[coin]
+		events = append(events, PositionChangeEvent{
+			Type:            PositionChangeClosed,
+			Address:         address,
+			Coin:            coin,
+			OldSzi:          old.Szi,
+			OldLeverage:     old.Leverage,
+			OldLeverageType: old.LeverageType,
+			OldEntryPx:      old.EntryPx,
+			Time:            nowMs,
+		})
+	}
+
+	return events
+}
+
+func (w *Watcher) publishChange(evt PositionChangeEvent) {
+	// 频道名与事件类型名相同：position_closed / position_size_changed / position_flipped / position_leverage_changed
+	t := positionChangeTypes[evt.Type]
+	if err := w.bus.Publish(context.Background(), t.Name, t, evt); err != nil {
+		zap.S().Errorf("[watcher] redis publish %s error: %v", evt.Type, err)
+		return
+	}
+
+	zap.S().Infof("[watcher] %s: %s %s szi=%s->%s lev=%d->%d",
+		evt.Type, utility.Abbr(evt.Address), evt.Coin, evt.OldSzi, evt.NewSzi, evt.OldLeverage, evt.NewLeverage)
+}
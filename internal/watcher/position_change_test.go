@@ -0,0 +1,119 @@
+package watcher
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+func assetPosition(coin, szi string, leverage int) model.AssetPosition {
+	return model.AssetPosition{Position: model.Position{
+		Coin:     coin,
+		Szi:      szi,
+		Leverage: model.Leverage{Type: "cross", Value: leverage},
+	}}
+}
+
+func TestDiffHolding(t *testing.T) {
+	type change struct {
+		typ  string
+		coin string
+		pct  float64
+	}
+	tests := []struct {
+		name    string
+		old     map[string]model.CoinPosition
+		current []model.AssetPosition
+		want    []change
+	}{
+		{
+			name:    "new position is not a change",
+			current: []model.AssetPosition{assetPosition("BTC", "1", 10)},
+		},
+		{
+			name:    "unchanged",
+			old:     map[string]model.CoinPosition{"BTC": {Coin: "BTC", Szi: "1", Leverage: 10}},
+			current: []model.AssetPosition{assetPosition("BTC", "1", 10)},
+		},
+		{
+			name: "closed in coin order",
+			old: map[string]model.CoinPosition{
+				"SOL": {Coin: "SOL", Szi: "5"},
+				"BTC": {Coin: "BTC", Szi: "1"},
+			},
+			want: []change{{PositionChangeClosed, "BTC", 0}, {PositionChangeClosed, "SOL", 0}},
+		},
+		{
+			name:    "size increase above threshold",
+			old:     map[string]model.CoinPosition{"BTC": {Coin: "BTC", Szi: "1", Leverage: 10}},
+			current: []model.AssetPosition{assetPosition("BTC", "1.5", 10)},
+			want:    []change{{PositionChangeSizeChanged, "BTC", 50}},
+		},
+		{
+			name:    "short reduced below threshold",
+			old:     map[string]model.CoinPosition{"BTC": {Coin: "BTC", Szi: "-1", Leverage: 10}},
+			current: []model.AssetPosition{assetPosition("BTC", "-0.95", 10)},
+		},
+		{
+			name:    "short reduced above threshold",
+			old:     map[string]model.CoinPosition{"BTC": {Coin: "BTC", Szi: "-2", Leverage: 10}},
+			current: []model.AssetPosition{assetPosition("BTC", "-1.5", 10)},
+			want:    []change{{PositionChangeSizeChanged, "BTC", -25}},
+		},
+		{
+			name:    "flip does not also report size change",
+			old:     map[string]model.CoinPosition{"ETH": {Coin: "ETH", Szi: "3", Leverage: 5}},
+			current: []model.AssetPosition{assetPosition("ETH", "-6", 5)},
+			want:    []change{{PositionChangeFlipped, "ETH", 0}},
+		},
+		{
+			name:    "leverage changed",
+			old:     map[string]model.CoinPosition{"ETH": {Coin: "ETH", Szi: "3", Leverage: 5}},
+			current: []model.AssetPosition{assetPosition("ETH", "3", 20)},
+			want:    []change{{PositionChangeLeverageChanged, "ETH", 0}},
+		},
+		{
+			name:    "legacy holding without leverage",
+			old:     map[string]model.CoinPosition{"ETH": {Coin: "ETH", Szi: "3"}},
+			current: []model.AssetPosition{assetPosition("ETH", "3", 20)},
+		},
+		{
+			name:    "size and leverage changed together",
+			old:     map[string]model.CoinPosition{"ETH": {Coin: "ETH", Szi: "2", Leverage: 5}},
+			current: []model.AssetPosition{assetPosition("ETH", "1", 3)},
+			want:    []change{{PositionChangeSizeChanged, "ETH", -50}, {PositionChangeLeverageChanged, "ETH", 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffHolding("0xabc", tt.old, tt.current, 10, 1000)
+			if len(got) != len(tt.want) {
+				t.Fatalf("diffHolding() = %+v, want %d events", got, len(tt.want))
+			}
+			for i, w := range tt.want {
+				e := got[i]
+				if e.Type != w.typ || e.Coin != w.coin || math.Abs(e.SizeChangePct-w.pct) > 1e-9 {
+					t.Errorf("event %d = {%s %s %v}, want %+v", i, e.Type, e.Coin, e.SizeChangePct, w)
+				}
+				if e.Address != "0xabc" || e.Time != 1000 {
+					t.Errorf("event %d address/time = %s/%d", i, e.Address, e.Time)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffHoldingZeroThreshold(t *testing.T) {
+	old := map[string]model.CoinPosition{
+		"BTC": {Coin: "BTC", Szi: "1", Leverage: 10},
+		"ETH": {Coin: "ETH", Szi: "2", Leverage: 5},
+	}
+	current := []model.AssetPosition{assetPosition("BTC", "1", 10), assetPosition("ETH", "2.02", 5)}
+	for _, threshold := range []float64{0, -5} {
+		got := diffHolding("0xabc", old, current, threshold, 1000)
+		if len(got) != 1 || got[0].Coin != "ETH" || got[0].Type != PositionChangeSizeChanged {
+			t.Errorf("diffHolding(sizeChangePct=%v) = %+v, want one ETH size change", threshold, got)
+		}
+	}
+}
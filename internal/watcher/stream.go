@@ -0,0 +1,565 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/proxy"
+	"github.com/hypercopy/crawler/internal/utility"
+	"go.uber.org/zap"
+)
+
+const (
+	wsURL = "wss://api.hyperliquid.xyz/ws"
+
+	pingInterval       = 30 * time.Second
+	readTimeout        = 2 * pingInterval
+	reconnectBaseDelay = 3 * time.Second
+	reconnectMaxDelay  = 60 * time.Second
+	subscribeThrottle  = 10 * time.Millisecond
+	firstPushTimeout   = 30 * time.Second // 订阅后超过该时长仍未收到 webData2 推送的地址视为订阅失败
+	demoteCooldown     = time.Hour        // 订阅失败的地址在该时长内保持轮询，不再分配到空出的订阅位置
+
+	streamRefreshInterval = 10 * time.Minute // 重新加载排行榜、系统设置并调整订阅分配（集群成员变化时立即执行）
+	pollIdleDelay         = 10 * time.Second // 没有需要轮询的地址时的等待间隔
+)
+
+type wsMsg struct {
+	Method       string        `json:"method"`
+	Subscription *subscription `json:"subscription,omitempty"`
+}
+
+type subscription struct {
+	Type string `json:"type"`
+	User string `json:"user"`
+}
+
+type wsResponse struct {
+	Channel string          `json:"channel"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// webData2Data webData2 推送中 watcher 需要的部分
+type webData2Data struct {
+	User               string                    `json:"user"`
+	ClearinghouseState *model.ClearinghouseState `json:"clearinghouseState"`
+}
+
+// streamShard 一条 WebSocket 连接及其订阅的地址，单连接最多订阅 subsPerConn 个地址
+type streamShard struct {
+	idx      int
+	proxyURL string // 为空时直连
+
+	mu    sync.Mutex
+	addrs map[string]time.Time // 地址 -> 最近一次在当前连接上发送订阅的时间
+
+	connMu sync.Mutex
+	conn   *websocket.Conn
+}
+
+// streamer 推送模式的运行状态：地址按分片订阅 webData2，订阅不下的地址回退到轮询
+type streamer struct {
+	w           *Watcher
+	shards      []*streamShard
+	subsPerConn int
+	setting     atomic.Pointer[model.SystemSetting]
+
+	mu       sync.Mutex
+	holdings map[string]map[string]model.CoinPosition // address -> coin -> 上一次观测到的持仓
+	byLower  map[string]string                        // 小写地址 -> 排行榜中的地址
+	polled   map[string]bool                          // 回退到轮询的地址
+	lastPush map[string]time.Time                     // 地址 -> 最近一次收到 webData2 推送的时间
+	demoted  map[string]time.Time                     // 订阅失败降级为轮询的地址 -> 降级时间
+}
+
+// RunStream 以 WebSocket 推送模式运行：每条连接订阅至多 subsPerConn 个交易员的 webData2，
+// 有代理时每个代理一条连接（受 maxConns 限制，0 表示不限制），否则只建立一条直连；
+// 超出订阅容量或订阅被拒绝的地址按 rate 轮询 clearinghouseState
+func (w *Watcher) RunStream(proxyMgr *proxy.Manager, subsPerConn, maxConns int) {
+	shardCount := 1
+	if proxyMgr != nil && proxyMgr.Count() > 0 {
+		shardCount = proxyMgr.Count()
+	}
+	if maxConns > 0 && shardCount > maxConns {
+		shardCount = maxConns
+	}
+
+	st := &streamer{
+		w:           w,
+		subsPerConn: subsPerConn,
+		holdings:    make(map[string]map[string]model.CoinPosition),
+		byLower:     make(map[string]string),
+		polled:      make(map[string]bool),
+		lastPush:    make(map[string]time.Time),
+		demoted:     make(map[string]time.Time),
+	}
+	for i := 0; i < shardCount; i++ {
+		sh := &streamShard{idx: i, addrs: make(map[string]time.Time)}
+		if proxyMgr != nil {
+			if p := proxyMgr.GetByIndex(i); p != nil {
+				sh.proxyURL = proxy.ProxyURL(p)
+			}
+		}
+		st.shards = append(st.shards, sh)
+	}
+
+	zap.S().Infof("[watcher] stream mode: %d connections x %d subscriptions", shardCount, subsPerConn)
+
+	st.refresh()
+	for _, sh := range st.shards {
+		go st.wsLoop(sh)
+	}
+	go st.pollLoop()
+
+	t := time.NewTicker(streamRefreshInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-w.clusterChanged():
+		}
+		st.refresh()
+	}
+}
+
+// refresh 重新加载系统设置与排行榜，退订已不在榜上的地址，再由 assign 分配订阅
+func (st *streamer) refresh() {
+	setting := st.w.loadSetting()
+	st.setting.Store(&setting)
+
+	addresses, err := st.w.loadLeaders()
+	if err != nil {
+		zap.S().Errorf("[watcher] load leaderboard error: %v", err)
+		return
+	}
+	want := make(map[string]bool, len(addresses))
+	for _, a := range addresses {
+		want[a] = true
+	}
+
+	st.mu.Lock()
+	var missing []string
+	for _, a := range addresses {
+		if _, ok := st.holdings[a]; !ok {
+			missing = append(missing, a)
+		}
+	}
+	st.mu.Unlock()
+	var holdingMap map[string]map[string]model.CoinPosition
+	if len(missing) > 0 {
+		if holdingMap, err = st.w.loadHoldings(missing); err != nil {
+			zap.S().Errorf("[watcher] load holdings error: %v", err)
+			return
+		}
+	}
+
+	st.mu.Lock()
+	for _, a := range missing {
+		st.holdings[a] = holdingMap[a]
+		st.byLower[strings.ToLower(a)] = a
+	}
+	for a := range st.holdings {
+		if !want[a] {
+			delete(st.holdings, a)
+			delete(st.byLower, strings.ToLower(a))
+			delete(st.polled, a)
+			delete(st.lastPush, a)
+			delete(st.demoted, a)
+		}
+	}
+	st.mu.Unlock()
+
+	for _, sh := range st.shards {
+		for _, a := range sh.list() {
+			if !want[a] {
+				sh.unsubscribe(a)
+			}
+		}
+	}
+
+	subscribed := st.assign(addresses, time.Now())
+	st.mu.Lock()
+	polled := len(st.polled)
+	st.mu.Unlock()
+	zap.S().Infof("[watcher] stream refresh: %d traders, %d subscribed on %d connections, %d polled",
+		len(addresses), subscribed, len(st.shards), polled)
+}
+
+// assign 按排行榜顺序把尚未订阅的地址分配到有空余的连接，其余回退到轮询；返回已订阅的地址数。
+// 最近 demoteCooldown 内订阅失败的地址保持轮询，避免刚降级又被放回空出的位置、反复订阅失败
+func (st *streamer) assign(addresses []string, now time.Time) int {
+	subscribed := 0
+	for _, a := range addresses {
+		if st.shardOf(a) != nil {
+			subscribed++
+			continue
+		}
+		st.mu.Lock()
+		at, demoted := st.demoted[a]
+		if demoted && now.Sub(at) >= demoteCooldown {
+			delete(st.demoted, a)
+			demoted = false
+		}
+		st.mu.Unlock()
+		if !demoted {
+			if sh := st.freeShard(); sh != nil {
+				st.mu.Lock()
+				delete(st.polled, a)
+				st.mu.Unlock()
+				sh.subscribe(a)
+				subscribed++
+				continue
+			}
+		}
+		st.mu.Lock()
+		st.polled[a] = true
+		st.mu.Unlock()
+	}
+	return subscribed
+}
+
+func (st *streamer) shardOf(address string) *streamShard {
+	for _, sh := range st.shards {
+		if sh.has(address) {
+			return sh
+		}
+	}
+	return nil
+}
+
+func (st *streamer) freeShard() *streamShard {
+	for _, sh := range st.shards {
+		if sh.size() < st.subsPerConn {
+			return sh
+		}
+	}
+	return nil
+}
+
+// demote 订阅失败的地址从连接上移除，改为轮询；demoteCooldown 后才会重新尝试订阅
+func (st *streamer) demote(sh *streamShard, address, reason string) {
+	sh.unsubscribe(address)
+	st.mu.Lock()
+	st.polled[address] = true
+	st.demoted[address] = time.Now()
+	st.mu.Unlock()
+	zap.S().Warnf("[watcher] shard %d: subscription for %s %s, falling back to polling", sh.idx, utility.Abbr(address), reason)
+}
+
+// demoteSilent 订阅超过 firstPushTimeout 仍没有收到过推送的地址（被静默拒绝或超出服务端限制）改为轮询
+func (st *streamer) demoteSilent(sh *streamShard) {
+	now := time.Now()
+	for a, at := range sh.subscriptions() {
+		if now.Sub(at) < firstPushTimeout {
+			continue
+		}
+		st.mu.Lock()
+		last := st.lastPush[a]
+		st.mu.Unlock()
+		if last.Before(at) {
+			st.demote(sh, a, fmt.Sprintf("got no push within %v", firstPushTimeout))
+		}
+	}
+}
+
+// ---------- WebSocket ----------
+
+func (st *streamer) wsLoop(sh *streamShard) {
+	delay := reconnectBaseDelay
+	for {
+		start := time.Now()
+		if err := st.connectAndServe(sh); err != nil {
+			zap.S().Errorf("[watcher] shard %d ws error: %v, reconnect in %v", sh.idx, err, delay)
+		}
+		if time.Since(start) > reconnectMaxDelay {
+			delay = reconnectBaseDelay
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+func (st *streamer) connectAndServe(sh *streamShard) error {
+	c, err := sh.dial()
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+
+	sh.connMu.Lock()
+	sh.conn = c
+	sh.connMu.Unlock()
+
+	defer func() {
+		c.Close()
+		sh.connMu.Lock()
+		sh.conn = nil
+		sh.connMu.Unlock()
+	}()
+
+	addrs := sh.list()
+	for _, a := range addrs {
+		sh.resubscribe(a)
+		time.Sleep(subscribeThrottle)
+	}
+	zap.S().Infof("[watcher] shard %d ws connected, subscribed %d addresses", sh.idx, len(addrs))
+
+	// keep-alive ping，同时检查迟迟没有推送的订阅
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(pingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				sh.send(wsMsg{Method: "ping"})
+				st.demoteSilent(sh)
+			case <-done:
+				return
+			}
+		}
+	}()
+	defer close(done)
+
+	// read loop
+	for {
+		c.SetReadDeadline(time.Now().Add(readTimeout))
+		_, raw, err := c.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+
+		var resp wsResponse
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			continue
+		}
+
+		switch resp.Channel {
+		case "webData2":
+			st.handleWebData(resp.Data)
+		case "error":
+			st.handleError(sh, resp.Data)
+		}
+	}
+}
+
+// handleError 错误消息中带有本连接订阅的地址时，视为该地址订阅被拒绝；
+// 不带地址的拒绝由 demoteSilent 按推送超时处理
+func (st *streamer) handleError(sh *streamShard, data json.RawMessage) {
+	var msg string
+	if err := json.Unmarshal(data, &msg); err != nil {
+		msg = string(data)
+	}
+	lower := strings.ToLower(msg)
+
+	matched := false
+	for _, a := range sh.list() {
+		if strings.Contains(lower, strings.ToLower(a)) {
+			st.demote(sh, a, "rejected")
+			matched = true
+		}
+	}
+	if !matched {
+		zap.S().Warnf("[watcher] shard %d ws error message: %s", sh.idx, msg)
+	}
+}
+
+func (st *streamer) handleWebData(data json.RawMessage) {
+	var d webData2Data
+	if err := json.Unmarshal(data, &d); err != nil || d.ClearinghouseState == nil {
+		return
+	}
+
+	st.mu.Lock()
+	address, ok := st.byLower[strings.ToLower(d.User)]
+	oldCoins := st.holdings[address]
+	if ok {
+		st.lastPush[address] = time.Now()
+	}
+	st.mu.Unlock()
+	if !ok || !positionsChanged(oldCoins, d.ClearinghouseState.AssetPositions) {
+		return
+	}
+
+	current, err := st.w.handleState(address, oldCoins, d.ClearinghouseState, st.setting.Load())
+	if err != nil {
+		zap.S().Warnf("[watcher] %s error: %v", utility.Abbr(address), err)
+		return
+	}
+	st.store(address, current)
+}
+
+// positionsChanged webData2 每次推送都带完整账户状态，只有币种、仓位大小或杠杆变化时才需要处理
+func positionsChanged(oldCoins map[string]model.CoinPosition, current []model.AssetPosition) bool {
+	if len(oldCoins) != len(current) {
+		return true
+	}
+	for _, ap := range current {
+		p := ap.Position
+		old, ok := oldCoins[p.Coin]
+		if !ok || old.Szi != p.Szi || old.Leverage != p.Leverage.Value || old.LeverageType != p.Leverage.Type {
+			return true
+		}
+	}
+	return false
+}
+
+func (st *streamer) store(address string, positions model.CoinPositions) {
+	coinMap := make(map[string]model.CoinPosition, len(positions))
+	for _, p := range positions {
+		coinMap[p.Coin] = p
+	}
+	st.mu.Lock()
+	if _, ok := st.holdings[address]; ok {
+		st.holdings[address] = coinMap
+	}
+	st.mu.Unlock()
+}
+
+// ---------- polling fallback ----------
+
+func (st *streamer) pollLoop() {
+	interval := time.Second / time.Duration(st.w.rate)
+	client := st.w.newClient()
+
+	for {
+		st.mu.Lock()
+		addresses := make([]string, 0, len(st.polled))
+		for a := range st.polled {
+			addresses = append(addresses, a)
+		}
+		st.mu.Unlock()
+
+		if len(addresses) == 0 {
+			time.Sleep(pollIdleDelay)
+			continue
+		}
+
+		for _, address := range addresses {
+			start := time.Now()
+
+			st.mu.Lock()
+			polled := st.polled[address]
+			oldCoins := st.holdings[address]
+			st.mu.Unlock()
+			if polled {
+				current, err := st.w.processOne(client, address, oldCoins, st.setting.Load())
+				if err != nil {
+					zap.S().Warnf("[watcher] %s error: %v", utility.Abbr(address), err)
+				} else {
+					st.store(address, current)
+				}
+			}
+
+			elapsed := time.Since(start)
+			if elapsed < interval {
+				time.Sleep(interval - elapsed)
+			}
+		}
+	}
+}
+
+// ---------- shard ----------
+
+func (sh *streamShard) dial() (*websocket.Conn, error) {
+	dialer := *websocket.DefaultDialer
+	if sh.proxyURL != "" {
+		u, err := url.Parse(sh.proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy url: %w", err)
+		}
+		dialer.Proxy = http.ProxyURL(u)
+	}
+	c, _, err := dialer.Dial(wsURL, nil)
+	return c, err
+}
+
+func (sh *streamShard) subscribe(address string) {
+	sh.mu.Lock()
+	sh.addrs[address] = time.Now()
+	sh.mu.Unlock()
+	sh.sendSubscribe(address)
+}
+
+// resubscribe 重连后对仍在本连接上的地址重新订阅，并重置推送超时的起点
+func (sh *streamShard) resubscribe(address string) {
+	sh.mu.Lock()
+	_, ok := sh.addrs[address]
+	if ok {
+		sh.addrs[address] = time.Now()
+	}
+	sh.mu.Unlock()
+	if ok {
+		sh.sendSubscribe(address)
+	}
+}
+
+func (sh *streamShard) sendSubscribe(address string) {
+	sh.send(wsMsg{
+		Method:       "subscribe",
+		Subscription: &subscription{Type: "webData2", User: address},
+	})
+}
+
+func (sh *streamShard) unsubscribe(address string) {
+	sh.mu.Lock()
+	delete(sh.addrs, address)
+	sh.mu.Unlock()
+	sh.send(wsMsg{
+		Method:       "unsubscribe",
+		Subscription: &subscription{Type: "webData2", User: address},
+	})
+}
+
+func (sh *streamShard) has(address string) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	_, ok := sh.addrs[address]
+	return ok
+}
+
+// subscriptions 返回地址及其订阅发送时间的副本
+func (sh *streamShard) subscriptions() map[string]time.Time {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	out := make(map[string]time.Time, len(sh.addrs))
+	for a, at := range sh.addrs {
+		out[a] = at
+	}
+	return out
+}
+
+func (sh *streamShard) size() int {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return len(sh.addrs)
+}
+
+func (sh *streamShard) list() []string {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	out := make([]string, 0, len(sh.addrs))
+	for a := range sh.addrs {
+		out = append(out, a)
+	}
+	return out
+}
+
+// send 连接未建立时直接丢弃，重连后会按 addrs 重新订阅
+func (sh *streamShard) send(msg wsMsg) {
+	sh.connMu.Lock()
+	defer sh.connMu.Unlock()
+	if sh.conn == nil {
+		return
+	}
+	if err := sh.conn.WriteJSON(msg); err != nil {
+		zap.S().Warnf("[watcher] shard %d ws write: %v", sh.idx, err)
+	}
+}
@@ -0,0 +1,150 @@
+package watcher
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+func TestPositionsChanged(t *testing.T) {
+	old := map[string]model.CoinPosition{
+		"BTC": {Coin: "BTC", Szi: "1", Leverage: 10, LeverageType: "cross"},
+		"ETH": {Coin: "ETH", Szi: "-2", Leverage: 5, LeverageType: "cross"},
+	}
+	tests := []struct {
+		name    string
+		old     map[string]model.CoinPosition
+		current []model.AssetPosition
+		want    bool
+	}{
+		{"both empty", nil, nil, false},
+		{"same positions", old, []model.AssetPosition{assetPosition("ETH", "-2", 5), assetPosition("BTC", "1", 10)}, false},
+		{"opened", nil, []model.AssetPosition{assetPosition("BTC", "1", 10)}, true},
+		{"closed", old, []model.AssetPosition{assetPosition("BTC", "1", 10)}, true},
+		{"size changed", old, []model.AssetPosition{assetPosition("BTC", "1.1", 10), assetPosition("ETH", "-2", 5)}, true},
+		{"leverage changed", old, []model.AssetPosition{assetPosition("BTC", "1", 20), assetPosition("ETH", "-2", 5)}, true},
+		{"coin swapped", old, []model.AssetPosition{assetPosition("BTC", "1", 10), assetPosition("SOL", "-2", 5)}, true},
+		{
+			name: "margin mode changed",
+			old:  old,
+			current: []model.AssetPosition{
+				assetPosition("BTC", "1", 10),
+				{Position: model.Position{Coin: "ETH", Szi: "-2", Leverage: model.Leverage{Type: "isolated", Value: 5}}},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := positionsChanged(tt.old, tt.current); got != tt.want {
+				t.Errorf("positionsChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testStreamer(shards, subsPerConn int) *streamer {
+	st := &streamer{
+		subsPerConn: subsPerConn,
+		polled:      make(map[string]bool),
+		demoted:     make(map[string]time.Time),
+	}
+	for i := 0; i < shards; i++ {
+		st.shards = append(st.shards, &streamShard{idx: i, addrs: make(map[string]time.Time)})
+	}
+	return st
+}
+
+func addresses(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("0x%02d", i)
+	}
+	return out
+}
+
+func TestStreamerAssign(t *testing.T) {
+	tests := []struct {
+		name       string
+		shards     int
+		perConn    int
+		addresses  int
+		wantSubs   int
+		wantPolled int
+		wantSizes  []int
+	}{
+		{"all fit", 2, 3, 4, 4, 0, []int{3, 1}},
+		{"exact capacity", 2, 2, 4, 4, 0, []int{2, 2}},
+		{"overflow polled", 2, 2, 7, 4, 3, []int{2, 2}},
+		{"no capacity", 1, 0, 3, 0, 3, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := testStreamer(tt.shards, tt.perConn)
+			addrs := addresses(tt.addresses)
+			if got := st.assign(addrs, time.Now()); got != tt.wantSubs {
+				t.Errorf("assign() = %d subscribed, want %d", got, tt.wantSubs)
+			}
+			if len(st.polled) != tt.wantPolled {
+				t.Errorf("polled = %d, want %d", len(st.polled), tt.wantPolled)
+			}
+			for i, sh := range st.shards {
+				if sh.size() != tt.wantSizes[i] {
+					t.Errorf("shard %d size = %d, want %d", i, sh.size(), tt.wantSizes[i])
+				}
+			}
+			// 排行榜靠前的地址优先订阅
+			for i, a := range addrs {
+				if subscribed := st.shardOf(a) != nil; subscribed != (i < tt.wantSubs) {
+					t.Errorf("%s subscribed = %v", a, subscribed)
+				}
+			}
+			// 再次分配不重复订阅
+			if got := st.assign(addrs, time.Now()); got != tt.wantSubs {
+				t.Errorf("second assign() = %d subscribed, want %d", got, tt.wantSubs)
+			}
+		})
+	}
+}
+
+func TestStreamerAssignFreedSlot(t *testing.T) {
+	st := testStreamer(1, 2)
+	addrs := addresses(3)
+	now := time.Now()
+	st.assign(addrs, now)
+	if !st.polled[addrs[2]] {
+		t.Fatalf("%s should be polled", addrs[2])
+	}
+
+	// 地址下榜退订后，空出的位置分配给轮询中的地址
+	st.shards[0].unsubscribe(addrs[1])
+	if got := st.assign([]string{addrs[0], addrs[2]}, now); got != 2 {
+		t.Errorf("assign() = %d subscribed, want 2", got)
+	}
+	if st.shardOf(addrs[2]) == nil || st.polled[addrs[2]] {
+		t.Errorf("%s not moved to the freed slot", addrs[2])
+	}
+}
+
+func TestStreamerDemoteCooldown(t *testing.T) {
+	st := testStreamer(1, 2)
+	addrs := addresses(1)
+	st.assign(addrs, time.Now())
+	st.demote(st.shards[0], addrs[0], "rejected")
+	if st.shardOf(addrs[0]) != nil || !st.polled[addrs[0]] {
+		t.Fatalf("demoted address still subscribed")
+	}
+
+	demotedAt := st.demoted[addrs[0]]
+	if got := st.assign(addrs, demotedAt.Add(demoteCooldown-time.Second)); got != 0 || st.shardOf(addrs[0]) != nil {
+		t.Errorf("demoted address resubscribed within cooldown")
+	}
+	if got := st.assign(addrs, demotedAt.Add(demoteCooldown)); got != 1 || st.polled[addrs[0]] {
+		t.Errorf("demoted address not resubscribed after cooldown")
+	}
+	if _, ok := st.demoted[addrs[0]]; ok {
+		t.Errorf("cooldown entry not cleared")
+	}
+}
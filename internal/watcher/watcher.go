@@ -2,11 +2,11 @@ package watcher
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 	"time"
 
+	"github.com/hypercopy/crawler/internal/events"
 	"github.com/hypercopy/crawler/internal/hyperliquid"
 	"github.com/hypercopy/crawler/internal/model"
 	"github.com/redis/go-redis/v9"
@@ -15,9 +15,8 @@ import (
 )
 
 const (
-	redisChannel        = "new_positions"
-	marketAlertChannel  = "market_alert"
-	redisTimelineKey    = "watcher:new_position_timeline"
+	redisChannel       = "new_positions"
+	marketAlertChannel = "market_alert"
 )
 
 type NewPositionEvent struct {
@@ -36,23 +35,43 @@ type NewPositionEvent struct {
 	CumFundingAllTime     string `json:"cumFundingAllTime"`
 	CumFundingSinceOpen   string `json:"cumFundingSinceOpen"`
 	CumFundingSinceChange string `json:"cumFundingSinceChange"`
+
+	// 以下为发布时补充的交易员上下文（见 enrich）
+	Username             string   `json:"username"`
+	TwitterName          string   `json:"twitterName"`
+	Labels               []string `json:"labels"`
+	Score                float64  `json:"score"`       // allTime 综合评分（0~100）
+	StatsWindow          string   `json:"statsWindow"` // WinRate/TotalPnl/Sharpe 所属统计窗口
+	WinRate              string   `json:"winRate"`
+	TotalPnl             string   `json:"totalPnl"`
+	Sharpe               string   `json:"sharpe"`
+	AccountValue         string   `json:"accountValue"`
+	PositionPctOfAccount float64  `json:"positionPctOfAccount"` // 持仓价值占账户价值百分比
+	MarkPx               string   `json:"markPx"`               // 持仓价值/|仓位| 推算的标记价格
 }
 
 type Watcher struct {
-	db     *gorm.DB
-	rdb    *redis.Client
-	rate   int
-	offset int
-	limit  int
+	db            *gorm.DB
+	rdb           *redis.Client
+	bus           *events.Bus
+	rate          int
+	offset        int
+	limit         int
+	sizeChangePct float64  // 仓位变化超过该百分比才发布 size_changed 事件
+	cluster       *cluster // 为 nil 时不分片，监控排行榜中的全部地址
+	eventOpts     EventOptions
 }
 
-func New(db *gorm.DB, rdb *redis.Client, rate, offset, limit int) *Watcher {
+func New(db *gorm.DB, rdb *redis.Client, bus *events.Bus, rate, offset, limit int, sizeChangePct float64) *Watcher {
 	return &Watcher{
-		db:     db,
-		rdb:    rdb,
-		rate:   rate,
-		offset: offset,
-		limit:  limit,
+		db:            db,
+		rdb:           rdb,
+		bus:           bus,
+		rate:          rate,
+		offset:        offset,
+		limit:         limit,
+		sizeChangePct: sizeChangePct,
+		eventOpts:     EventOptions{StatsWindow: "allTime"},
 	}
 }
 
@@ -61,36 +80,21 @@ func (w *Watcher) Run() {
 	client := w.newClient()
 
 	for round := 1; ; round++ {
-		var setting model.SystemSetting
-		if err := w.db.First(&setting).Error; err != nil {
-			zap.S().Warnf("[watcher] load system setting error: %v, using defaults (5min / 3 positions)", err)
-			setting = model.SystemSetting{MarketMinutes: 5, MarketNewPositionCount: 3}
-		}
+		setting := w.loadSetting()
+		version := w.clusterVersion()
 
-		var leaders []model.Leaderboard
-		query := w.db.Order("vlm DESC")
-		if w.offset > 0 {
-			query = query.Offset(w.offset)
-		}
-		if w.limit > 0 {
-			query = query.Limit(w.limit)
-		}
-		if err := query.Find(&leaders).Error; err != nil {
+		addresses, err := w.loadLeaders()
+		if err != nil {
 			zap.S().Errorf("[watcher] load leaderboard error: %v, retrying in 10s", err)
 			time.Sleep(10 * time.Second)
 			continue
 		}
-		if len(leaders) == 0 {
+		if len(addresses) == 0 {
 			zap.S().Warn("[watcher] no traders in leaderboard, retrying in 30s")
 			time.Sleep(30 * time.Second)
 			continue
 		}
 
-		addresses := make([]string, len(leaders))
-		for i, l := range leaders {
-			addresses[i] = l.EthAddress
-		}
-
 		holdingMap, err := w.loadHoldings(addresses)
 		if err != nil {
 			zap.S().Errorf("[watcher] load holdings error: %v, retrying in 10s", err)
@@ -104,10 +108,14 @@ func (w *Watcher) Run() {
 
 		succeeded, failed := 0, 0
 		for i, address := range addresses {
+			if w.clusterVersion() != version {
+				zap.S().Infof("[watcher] round %d: cluster members changed, restarting round", round)
+				break
+			}
 			start := time.Now()
 
 			oldCoins := holdingMap[address]
-			if err := w.processOne(client, address, oldCoins, &setting); err != nil {
+			if _, err := w.processOne(client, address, oldCoins, &setting); err != nil {
 				zap.S().Warnf("[watcher] %s error: %v", address[:10], err)
 				failed++
 			} else {
@@ -129,41 +137,81 @@ func (w *Watcher) Run() {
 	}
 }
 
+func (w *Watcher) loadSetting() model.SystemSetting {
+	var setting model.SystemSetting
+	if err := w.db.First(&setting).Error; err != nil {
+		zap.S().Warnf("[watcher] load system setting error: %v, using defaults (5min / 3 positions)", err)
+		setting = model.SystemSetting{MarketMinutes: 5, MarketNewPositionCount: 3}
+	}
+	return setting
+}
+
+// loadLeaders 按成交量排序取排行榜中需要监控的地址（受 offset/limit 限制），加入集群时只保留本实例负责的部分
+func (w *Watcher) loadLeaders() ([]string, error) {
+	var leaders []model.Leaderboard
+	query := w.db.Order("vlm DESC")
+	if w.offset > 0 {
+		query = query.Offset(w.offset)
+	}
+	if w.limit > 0 {
+		query = query.Limit(w.limit)
+	}
+	if err := query.Find(&leaders).Error; err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, len(leaders))
+	for i, l := range leaders {
+		addresses[i] = l.EthAddress
+	}
+	if w.cluster != nil {
+		addresses = w.cluster.filter(addresses)
+	}
+	return addresses, nil
+}
+
 func (w *Watcher) newClient() *hyperliquid.Client {
 	return hyperliquid.NewClient()
 }
 
-func (w *Watcher) loadHoldings(addresses []string) (map[string]map[string]string, error) {
+func (w *Watcher) loadHoldings(addresses []string) (map[string]map[string]model.CoinPosition, error) {
 	var holdings []model.TraderCoinHolding
 	if err := w.db.Where("address IN ?", addresses).Find(&holdings).Error; err != nil {
 		return nil, err
 	}
 
-	result := make(map[string]map[string]string, len(holdings))
+	result := make(map[string]map[string]model.CoinPosition, len(holdings))
 	for _, h := range holdings {
-		coinMap := make(map[string]string, len(h.Positions))
+		coinMap := make(map[string]model.CoinPosition, len(h.Positions))
 		for _, p := range h.Positions {
-			coinMap[p.Coin] = p.Szi
+			coinMap[p.Coin] = p
 		}
 		result[h.Address] = coinMap
 	}
 	return result, nil
 }
 
-func (w *Watcher) processOne(client *hyperliquid.Client, address string, oldCoins map[string]string, setting *model.SystemSetting) error {
+func (w *Watcher) processOne(client *hyperliquid.Client, address string, oldCoins map[string]model.CoinPosition, setting *model.SystemSetting) (model.CoinPositions, error) {
 	chState, err := client.FetchClearinghouseState(address)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return w.handleState(address, oldCoins, chState, setting)
+}
 
+// handleState 对比上一轮持仓与最新 clearinghouseState：写回持仓币种、发布新开仓及持仓变更事件，返回最新持仓
+func (w *Watcher) handleState(address string, oldCoins map[string]model.CoinPosition, chState *model.ClearinghouseState, setting *model.SystemSetting) (model.CoinPositions, error) {
 	var newEvents []NewPositionEvent
 	currentPositions := make(model.CoinPositions, 0, len(chState.AssetPositions))
 
 	for _, ap := range chState.AssetPositions {
 		p := ap.Position
 		currentPositions = append(currentPositions, model.CoinPosition{
-			Coin: p.Coin,
-			Szi:  p.Szi,
+			Coin:         p.Coin,
+			Szi:          p.Szi,
+			Leverage:     p.Leverage.Value,
+			LeverageType: p.Leverage.Type,
+			EntryPx:      p.EntryPx,
 		})
 
 		if _, exists := oldCoins[p.Coin]; !exists {
@@ -191,19 +239,25 @@ func (w *Watcher) processOne(client *hyperliquid.Client, address string, oldCoin
 		}
 	}
 
+	changes := diffHolding(address, oldCoins, chState.AssetPositions, w.sizeChangePct, time.Now().UnixMilli())
+
 	sort.Slice(currentPositions, func(i, j int) bool {
 		return currentPositions[i].Coin < currentPositions[j].Coin
 	})
 
 	if err := w.upsertHolding(address, currentPositions); err != nil {
-		return fmt.Errorf("upsert holding: %w", err)
+		return nil, fmt.Errorf("upsert holding: %w", err)
 	}
 
 	for _, evt := range newEvents {
+		w.enrich(&evt, chState)
 		w.trackAndPublish(evt, setting)
 	}
+	for _, evt := range changes {
+		w.publishChange(evt)
+	}
 
-	return nil
+	return currentPositions, nil
 }
 
 func (w *Watcher) upsertHolding(address string, positions model.CoinPositions) error {
@@ -219,60 +273,18 @@ func (w *Watcher) upsertHolding(address string, positions model.CoinPositions) e
 func (w *Watcher) trackAndPublish(evt NewPositionEvent, setting *model.SystemSetting) {
 	ctx := context.Background()
 
-	data, err := json.Marshal(evt)
-	if err != nil {
-		zap.S().Errorf("[watcher] marshal event error: %v", err)
+	// 过滤只影响 new_positions 的发布，行情预警仍统计全部新开仓
+	if !w.eventOpts.allow(evt) {
+		w.trackMarket(evt, setting)
 		return
 	}
-	if err := w.rdb.Publish(ctx, redisChannel, string(data)).Err(); err != nil {
-		zap.S().Errorf("[watcher] redis publish new position error: %v", err)
-	}
-
-	now := float64(time.Now().UnixMilli())
-	member := fmt.Sprintf("%s:%s:%d", evt.Address, evt.Coin, int64(now))
-
-	w.rdb.ZAdd(ctx, redisTimelineKey, redis.Z{Score: now, Member: member})
 
-	windowStart := now - float64(setting.MarketMinutes)*60*1000
-	w.rdb.ZRemRangeByScore(ctx, redisTimelineKey, "-inf", fmt.Sprintf("%f", windowStart))
-
-	count, err := w.rdb.ZCard(ctx, redisTimelineKey).Result()
-	if err != nil {
-		zap.S().Errorf("[watcher] redis zcard error: %v", err)
+	if err := w.bus.Publish(ctx, redisChannel, events.NewPosition, evt); err != nil {
+		zap.S().Errorf("[watcher] publish new position error: %v", err)
 	}
 
-	zap.S().Infof("[watcher] new position: %s %s szi=%s entry=%s (count=%d/%d in %dmin)",
-		evt.Address[:10], evt.Coin, evt.Szi, evt.EntryPx,
-		count, setting.MarketNewPositionCount, setting.MarketMinutes)
-
-	if count >= int64(setting.MarketNewPositionCount) {
-		w.publishMarketAlert(count, setting)
-	}
-}
-
-type MarketAlert struct {
-	Count    int64 `json:"count"`
-	Minutes  int   `json:"minutes"`
-	Threshold int  `json:"threshold"`
-}
-
-func (w *Watcher) publishMarketAlert(count int64, setting *model.SystemSetting) {
-	alert := MarketAlert{
-		Count:     count,
-		Minutes:   setting.MarketMinutes,
-		Threshold: setting.MarketNewPositionCount,
-	}
-	data, err := json.Marshal(alert)
-	if err != nil {
-		zap.S().Errorf("[watcher] marshal market alert error: %v", err)
-		return
-	}
-
-	if err := w.rdb.Publish(context.Background(), marketAlertChannel, string(data)).Err(); err != nil {
-		zap.S().Errorf("[watcher] redis publish market alert error: %v", err)
-		return
-	}
+	zap.S().Infof("[watcher] new position: %s %s szi=%s entry=%s value=%s",
+		evt.Address[:10], evt.Coin, evt.Szi, evt.EntryPx, evt.PositionValue)
 
-	zap.S().Warnf("[watcher] MARKET ALERT: %d new positions in %d minutes (threshold=%d)",
-		count, setting.MarketMinutes, setting.MarketNewPositionCount)
+	w.trackMarket(evt, setting)
 }
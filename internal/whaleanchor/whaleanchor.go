@@ -0,0 +1,187 @@
+package whaleanchor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/hypercopy/crawler/internal/hyperliquid"
+	"github.com/hypercopy/crawler/internal/model"
+	"github.com/hypercopy/crawler/internal/utility"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// depthBand 计算盘口深度的价格范围（中间价上下 1%）
+const depthBand = 0.01
+
+// bookSigFigs 依次尝试的盘口聚合精度：精度越高越准确，但每侧仅 bookLevels 档，可能覆盖不到 ±1%
+var bookSigFigs = []int{5, 4, 3, 2}
+
+// bookLevels l2Book 每侧返回的最大档位数
+const bookLevels = 20
+
+// Formula 巨鲸阈值公式：threshold = max(VolumeCoef*24h成交额, OICoef*OI, DepthCoef*±1%深度, MinThreshold)
+type Formula struct {
+	VolumeCoef   float64
+	OICoef       float64
+	DepthCoef    float64
+	MinThreshold float64
+}
+
+// DefaultFormula whale_anchor 表注释中的默认系数
+var DefaultFormula = Formula{VolumeCoef: 0.004, OICoef: 0.01, DepthCoef: 0.3}
+
+// Job 周期性拉取永续合约行情与盘口，按公式计算每个币种的巨鲸仓位阈值并写入 whale_anchor
+type Job struct {
+	db       *gorm.DB
+	client   *hyperliquid.Client
+	formula  Formula
+	interval time.Duration
+	delay    time.Duration
+}
+
+func New(db *gorm.DB, client *hyperliquid.Client, formula Formula, interval, delay time.Duration) *Job {
+	return &Job{
+		db:       db,
+		client:   client,
+		formula:  formula,
+		interval: interval,
+		delay:    delay,
+	}
+}
+
+func (j *Job) Run() {
+	for round := 1; ; round++ {
+		if err := j.runOnce(); err != nil {
+			zap.S().Errorf("[whaleanchor] round %d error: %v", round, err)
+		}
+		time.Sleep(j.interval)
+	}
+}
+
+func (j *Job) runOnce() error {
+	meta, ctxs, err := j.client.FetchMetaAndAssetCtxs()
+	if err != nil {
+		return err
+	}
+
+	saved := 0
+	for i, asset := range meta.Universe {
+		if asset.IsDelisted || i >= len(ctxs) {
+			continue
+		}
+		ctx := ctxs[i]
+		markPx, _ := strconv.ParseFloat(ctx.MarkPx, 64)
+		if markPx <= 0 {
+			continue
+		}
+		volume, _ := strconv.ParseFloat(ctx.DayNtlVlm, 64)
+		oiCoins, _ := strconv.ParseFloat(ctx.OpenInterest, 64)
+		oi := oiCoins * markPx
+
+		time.Sleep(j.delay)
+		depth, err := j.fetchDepth(asset.Name)
+		if err != nil {
+			zap.S().Warnf("[whaleanchor] %s depth error: %v", asset.Name, err)
+			continue
+		}
+
+		anchor := j.formula.anchor(asset.Name, volume, oi, depth)
+		if err := j.upsert(&anchor); err != nil {
+			zap.S().Errorf("[whaleanchor] %s upsert error: %v", asset.Name, err)
+			continue
+		}
+		saved++
+	}
+	zap.S().Infof("[whaleanchor] updated %d/%d symbols", saved, len(meta.Universe))
+	return nil
+}
+
+func (f Formula) anchor(symbol string, volume, oi, depth float64) model.WhaleAnchor {
+	valVolume := f.VolumeCoef * volume
+	valOI := f.OICoef * oi
+	valDepth := f.DepthCoef * depth
+	threshold := math.Max(math.Max(valVolume, valOI), math.Max(valDepth, f.MinThreshold))
+
+	return model.WhaleAnchor{
+		Symbol:         symbol,
+		Volume24h:      utility.FmtFloat(volume),
+		OpenInterest:   utility.FmtFloat(oi),
+		Depth1pct:      utility.FmtFloat(depth),
+		ValVolume:      utility.FmtFloat(valVolume),
+		ValOI:          utility.FmtFloat(valOI),
+		ValDepth:       utility.FmtFloat(valDepth),
+		WhaleThreshold: utility.FmtFloat(threshold),
+	}
+}
+
+// fetchDepth 返回中间价 ±1% 内买卖盘名义价值合计；从高精度开始尝试，
+// 直到两侧档位都覆盖到 ±1% 边界（或整侧盘口不足 bookLevels 档）
+func (j *Job) fetchDepth(coin string) (float64, error) {
+	var last float64
+	for i, sig := range bookSigFigs {
+		if i > 0 {
+			time.Sleep(j.delay)
+		}
+		book, err := j.client.FetchL2Book(coin, sig)
+		if err != nil {
+			return 0, err
+		}
+		depth, covered, ok := bookDepth(book)
+		if !ok {
+			return 0, fmt.Errorf("empty book")
+		}
+		last = depth
+		if covered {
+			return depth, nil
+		}
+	}
+	return last, nil
+}
+
+// bookDepth 计算 ±1% 深度；covered 表示两侧档位均已覆盖到边界
+func bookDepth(book *model.L2Book) (depth float64, covered bool, ok bool) {
+	bids, asks := book.Levels[0], book.Levels[1]
+	if len(bids) == 0 || len(asks) == 0 {
+		return 0, false, false
+	}
+	bestBid, _ := strconv.ParseFloat(bids[0].Px, 64)
+	bestAsk, _ := strconv.ParseFloat(asks[0].Px, 64)
+	mid := (bestBid + bestAsk) / 2
+	if mid <= 0 {
+		return 0, false, false
+	}
+	low, high := mid*(1-depthBand), mid*(1+depthBand)
+
+	bidDepth, bidCovered := sideDepth(bids, func(px float64) bool { return px >= low })
+	askDepth, askCovered := sideDepth(asks, func(px float64) bool { return px <= high })
+	return bidDepth + askDepth, bidCovered && askCovered, true
+}
+
+// sideDepth 累加在范围内的档位；遇到范围外的档位或不足 bookLevels 档（已是完整盘口）视为已覆盖
+func sideDepth(levels []model.L2Level, inBand func(px float64) bool) (float64, bool) {
+	depth := 0.0
+	for _, l := range levels {
+		px, _ := strconv.ParseFloat(l.Px, 64)
+		if !inBand(px) {
+			return depth, true
+		}
+		sz, _ := strconv.ParseFloat(l.Sz, 64)
+		depth += px * sz
+	}
+	return depth, len(levels) < bookLevels
+}
+
+func (j *Job) upsert(a *model.WhaleAnchor) error {
+	a.UpdatedAt = time.Now()
+	return j.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "symbol"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"volume24h", "open_interest", "depth1pct", "val_volume", "val_oi",
+			"val_depth", "whale_threshold", "updated_at",
+		}),
+	}).Create(a).Error
+}
@@ -0,0 +1,118 @@
+package whaleanchor
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/hypercopy/crawler/internal/model"
+)
+
+// levels 生成从 start 起每档间隔 step、每档数量 sz 的 n 档盘口
+func levels(start, step, sz float64, n int) []model.L2Level {
+	out := make([]model.L2Level, n)
+	for i := range out {
+		out[i] = model.L2Level{
+			Px: strconv.FormatFloat(start+step*float64(i), 'f', -1, 64),
+			Sz: strconv.FormatFloat(sz, 'f', -1, 64),
+		}
+	}
+	return out
+}
+
+func TestSideDepth(t *testing.T) {
+	below := func(limit float64) func(float64) bool { return func(px float64) bool { return px <= limit } }
+	tests := []struct {
+		name        string
+		levels      []model.L2Level
+		limit       float64
+		wantDepth   float64
+		wantCovered bool
+	}{
+		{"empty side", nil, 101, 0, true},
+		{"stops at first level outside the band", levels(100, 0.5, 1, 6), 101, 100 + 100.5 + 101, true},
+		{"partial book fully in band", levels(100, 0.01, 2, 5), 101, 2 * (100 + 100.01 + 100.02 + 100.03 + 100.04), true},
+		{"full book in band not covered", levels(100, 0.01, 1, bookLevels), 101, 20*100 + 0.01*190, false},
+		{"full book crossing the band", levels(100, 0.1, 1, bookLevels), 101, 11*100 + 0.1*55, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth, covered := sideDepth(tt.levels, below(tt.limit))
+			if math.Abs(depth-tt.wantDepth) > 1e-6 || covered != tt.wantCovered {
+				t.Errorf("sideDepth() = (%v, %v), want (%v, %v)", depth, covered, tt.wantDepth, tt.wantCovered)
+			}
+		})
+	}
+}
+
+func TestBookDepth(t *testing.T) {
+	tests := []struct {
+		name        string
+		bids, asks  []model.L2Level
+		wantDepth   float64
+		wantCovered bool
+		wantOK      bool
+	}{
+		{name: "no bids", asks: levels(100, 1, 1, 3)},
+		{name: "no asks", bids: levels(100, -1, 1, 3)},
+		{name: "zero mid", bids: levels(0, 0, 1, 1), asks: levels(0, 0, 1, 1)},
+		{
+			// mid = 100，范围 [99, 101]
+			name:        "both sides covered",
+			bids:        levels(99.5, -0.5, 2, 4), // 99.5, 99, 98.5（范围外）
+			asks:        levels(100.5, 0.5, 1, 4), // 100.5, 101, 101.5（范围外）
+			wantDepth:   2*99.5 + 2*99 + 100.5 + 101,
+			wantCovered: true,
+			wantOK:      true,
+		},
+		{
+			name:        "one side truncated at 20 levels",
+			bids:        levels(99.99, -0.01, 1, bookLevels),
+			asks:        levels(100.01, 2, 1, 2),
+			wantDepth:   20*99.99 - 0.01*190 + 100.01,
+			wantCovered: false,
+			wantOK:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := &model.L2Book{Levels: [2][]model.L2Level{tt.bids, tt.asks}}
+			depth, covered, ok := bookDepth(book)
+			if ok != tt.wantOK || covered != tt.wantCovered || math.Abs(depth-tt.wantDepth) > 1e-6 {
+				t.Errorf("bookDepth() = (%v, %v, %v), want (%v, %v, %v)",
+					depth, covered, ok, tt.wantDepth, tt.wantCovered, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFormulaAnchor(t *testing.T) {
+	f := Formula{VolumeCoef: 0.004, OICoef: 0.01, DepthCoef: 0.3, MinThreshold: 50_000}
+	tests := []struct {
+		name            string
+		volume, oi, dep float64
+		wantThreshold   float64
+		wantVal         [3]float64 // volume / oi / depth 分项
+	}{
+		{"volume wins", 100_000_000, 10_000_000, 100_000, 400_000, [3]float64{400_000, 100_000, 30_000}},
+		{"oi wins", 10_000_000, 50_000_000, 100_000, 500_000, [3]float64{40_000, 500_000, 30_000}},
+		{"depth wins", 1_000_000, 1_000_000, 1_000_000, 300_000, [3]float64{4_000, 10_000, 300_000}},
+		{"min threshold", 1_000, 1_000, 1_000, 50_000, [3]float64{4, 10, 300}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := f.anchor("BTC", tt.volume, tt.oi, tt.dep)
+			num := func(s string) float64 { v, _ := strconv.ParseFloat(s, 64); return v }
+			if a.Symbol != "BTC" || math.Abs(num(a.WhaleThreshold)-tt.wantThreshold) > 1e-6 {
+				t.Errorf("anchor() threshold = %s, want %v", a.WhaleThreshold, tt.wantThreshold)
+			}
+			got := [3]float64{num(a.ValVolume), num(a.ValOI), num(a.ValDepth)}
+			for i := range got {
+				if math.Abs(got[i]-tt.wantVal[i]) > 1e-6 {
+					t.Errorf("anchor() components = %v, want %v", got, tt.wantVal)
+					break
+				}
+			}
+		})
+	}
+}